@@ -0,0 +1,92 @@
+// Filename: cmd/api/answers.go
+
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"universityforum.miguelavila.net/internals/data"
+)
+
+// acceptReplyHandler for the "POST /v1/replies/:id/accept" endpoint
+func (app *application) acceptReplyHandler(w http.ResponseWriter, r *http.Request) {
+	app.setAcceptedReply(w, r, true)
+}
+
+// unacceptReplyHandler for the "DELETE /v1/replies/:id/accept" endpoint
+func (app *application) unacceptReplyHandler(w http.ResponseWriter, r *http.Request) {
+	app.setAcceptedReply(w, r, false)
+}
+
+// setAcceptedReply() marks or unmarks a reply as the accepted answer of its
+// forum. Only top level replies of question forums can be accepted, and only
+// by the author of the forum or a moderator. Unmarking a reply that is not the
+// accepted answer is a conflict
+func (app *application) setAcceptedReply(w http.ResponseWriter, r *http.Request, accepted bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	reply, err := app.models.Reply.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	forum, err := app.models.Forum.GetVisible(reply.ForumID, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	allowed, err := app.isOwnerOrModerator(app.contextGetUser(r), forum.AuthorID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		app.notPermittedResponse(w, r)
+		return
+	}
+	if forum.Kind != data.ForumKindQuestion {
+		app.notQuestionResponse(w, r)
+		return
+	}
+	// Nested replies are comments on an answer rather than answers
+	if reply.ParentID != 0 {
+		app.failedValidationResponse(w, r, map[string]string{"reply": "must be a top level reply"})
+		return
+	}
+
+	if accepted {
+		err = app.models.Forum.AcceptReply(forum.ID, reply.ID)
+	} else {
+		err = app.models.Forum.UnacceptReply(forum.ID, reply.ID)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrNotAccepted):
+			app.notAcceptedResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	reply.Accepted = accepted
+	err = app.writeJSON(w, http.StatusOK, envelope{"reply": reply}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
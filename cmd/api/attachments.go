@@ -0,0 +1,362 @@
+// Filename: cmd/api/attachments.go
+
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"universityforum.miguelavila.net/internals/data"
+	"universityforum.miguelavila.net/internals/storage"
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+// The file types that may be attached and the largest size allowed for each
+var attachmentTypes = map[string]int64{
+	"image/png":       5 << 20,
+	"image/jpeg":      5 << 20,
+	"image/gif":       5 << 20,
+	"image/webp":      5 << 20,
+	"application/pdf": 10 << 20,
+}
+
+// The largest size allowed for any attachment
+const maxAttachmentSize = 10 << 20
+
+// createForumAttachmentHandler for the "POST /v1/forums/:id/attachments" endpoint
+func (app *application) createForumAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	forum, err := app.models.Forum.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	// Only the author or a moderator may attach files to the forum
+	allowed, err := app.isOwnerOrModerator(app.contextGetUser(r), forum.AuthorID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		app.notPermittedResponse(w, r)
+		return
+	}
+	app.uploadAttachment(w, r, &data.Attachment{ForumID: forum.ID})
+}
+
+// createReplyAttachmentHandler for the "POST /v1/replies/:id/attachments" endpoint
+func (app *application) createReplyAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	reply, err := app.models.Reply.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	// Only the author or a moderator may attach files to the reply
+	allowed, err := app.isOwnerOrModerator(app.contextGetUser(r), reply.UserID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		app.notPermittedResponse(w, r)
+		return
+	}
+	app.uploadAttachment(w, r, &data.Attachment{ReplyID: reply.ID})
+}
+
+// uploadAttachment() reads the "file" field of a multipart request, checks
+// its type and size, and stores it. Content that is already stored, as found
+// by its SHA-256, is not stored again
+func (app *application) uploadAttachment(w http.ResponseWriter, r *http.Request, attachment *data.Attachment) {
+	// Uploads are not JSON so the limit of readJSON() does not apply. Leave
+	// some room for the multipart framing
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentSize+1<<20)
+	err := r.ParseMultipartForm(1 << 20)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesError):
+			app.fileTooLargeResponse(w, r, maxAttachmentSize)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+	// Remove any temporary files the form was spooled to
+	defer r.MultipartForm.RemoveAll()
+
+	v := validator.New()
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		switch {
+		case errors.Is(err, http.ErrMissingFile):
+			v.AddError("file", "must be provided")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+	defer file.Close()
+
+	// Sniff the type from the content, the type sent by the client is not
+	// trusted
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// Keep only the last element of the name the client sent
+	filename := path.Base(strings.ReplaceAll(header.Filename, `\`, "/"))
+
+	if validateAttachmentFile(v, contentType, header.Size, filename); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Hash the content to find out where it is stored
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	attachment.UploaderID = app.contextGetUser(r).ID
+	attachment.Filename = filename
+	attachment.ContentType = contentType
+	attachment.Size = size
+	attachment.SHA256 = hash.Sum(nil)
+
+	// Record the attachment before storing its content, see Insert()
+	err = app.models.Attachments.Insert(attachment)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.storeAttachment(attachment, file)
+	if err != nil {
+		// Do not leave an attachment without content behind
+		if err := app.models.Attachments.Delete(attachment.ID); err != nil {
+			app.logger.PrintError(err, nil)
+		}
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/attachments/%d", attachment.ID))
+	err = app.writeJSON(w, http.StatusCreated, envelope{"attachment": attachment}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// validateAttachmentFile() checks the sniffed type, the size and the file
+// name of an upload against the limits of its type
+func validateAttachmentFile(v *validator.Validator, contentType string, size int64, filename string) {
+	limit, ok := attachmentTypes[contentType]
+	v.Check(ok, "file", "must be a PNG, JPEG, GIF or WebP image or a PDF document")
+	v.Check(!ok || size <= limit, "file", fmt.Sprintf("must not be more than %d bytes long", limit))
+	v.Check(size > 0, "file", "must not be empty")
+	v.Check(filename != "" && filename != "." && filename != "/", "file", "must have a file name")
+	v.Check(len(filename) <= 255, "file", "must have a file name of at most 255 bytes")
+}
+
+// storeAttachment() stores the content of an attachment unless an identical
+// file was uploaded before
+func (app *application) storeAttachment(attachment *data.Attachment, file io.ReadSeeker) error {
+	exists, err := app.storage.Exists(attachment.StorageKey())
+	if err != nil || exists {
+		return err
+	}
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	return app.storage.Put(attachment.StorageKey(), file)
+}
+
+// The listForumAttachmentsHandler() returns the files attached to a forum
+func (app *application) listForumAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	_, err = app.models.Forum.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	attachments, err := app.models.Attachments.GetAllFor(id, 0)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"attachments": attachments}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The listReplyAttachmentsHandler() returns the files attached to a reply
+func (app *application) listReplyAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	_, err = app.models.Reply.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	attachments, err := app.models.Attachments.GetAllFor(0, id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"attachments": attachments}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The showAttachmentHandler() sends the content of an attachment. An
+// attachment can be downloaded as long as its forum or reply can be seen
+func (app *application) showAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	attachment, err := app.models.Attachments.Get(id)
+	if err == nil {
+		// Check that the parent can still be seen
+		if attachment.ForumID != 0 {
+			_, err = app.models.Forum.GetVisible(attachment.ForumID, app.contextGetUser(r).ID)
+		} else {
+			_, err = app.models.Reply.GetVisible(attachment.ReplyID, app.contextGetUser(r).ID)
+		}
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	content, err := app.storage.Get(attachment.StorageKey())
+	if err != nil {
+		switch {
+		// The content was lost, so there is nothing to show
+		case errors.Is(err, storage.ErrNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	defer content.Close()
+
+	// Images are shown in the browser, anything else is downloaded
+	disposition := "attachment"
+	if strings.HasPrefix(attachment.ContentType, "image/") {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = io.Copy(w, content)
+	if err != nil {
+		// The headers are already sent, so only log the error
+		app.logError(r, err)
+	}
+}
+
+// The contents of deleted attachments are removed from the storage this
+// often, going through this many of them at a time
+const (
+	attachmentCleanupInterval  = 10 * time.Minute
+	attachmentCleanupBatchSize = 100
+)
+
+// removeDeletedAttachments() removes the content of the attachments that
+// went away with their forum or reply, once no other attachment shares it.
+// It runs until the server shuts down
+func (app *application) removeDeletedAttachments() {
+	ticker := time.NewTicker(attachmentCleanupInterval)
+	defer ticker.Stop()
+	for {
+		// Keep going while whole batches are queued
+		for {
+			count, err := app.models.Attachments.RemoveDeleted(attachmentCleanupBatchSize, app.storage.Delete)
+			if err != nil {
+				app.logger.PrintError(err, nil)
+				break
+			}
+			if count < attachmentCleanupBatchSize {
+				break
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-app.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Filename: cmd/api/attachments_test.go
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+func TestValidateAttachmentFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		size        int64
+		filename    string
+		valid       bool
+	}{
+		{"image", "image/png", 1 << 20, "photo.png", true},
+		{"image at the limit", "image/jpeg", 5 << 20, "photo.jpg", true},
+		{"image over the limit", "image/webp", 5<<20 + 1, "photo.webp", false},
+		{"pdf over the image limit", "application/pdf", 8 << 20, "notes.pdf", true},
+		{"pdf at the limit", "application/pdf", 10 << 20, "notes.pdf", true},
+		{"pdf over the limit", "application/pdf", 10<<20 + 1, "notes.pdf", false},
+		{"other type", "text/html", 100, "page.html", false},
+		{"empty", "image/gif", 0, "empty.gif", false},
+		{"no file name", "image/png", 100, ".", false},
+		{"root as file name", "image/png", 100, "/", false},
+		{"long file name", "image/png", 100, strings.Repeat("a", 252) + ".png", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			validateAttachmentFile(v, tt.contentType, tt.size, tt.filename)
+			if v.Valid() != tt.valid {
+				t.Errorf("got valid %t; want %t (errors %v)", v.Valid(), tt.valid, v.Errors)
+			}
+		})
+	}
+}
+
+// No type may be larger than the request body allows
+func TestAttachmentTypeLimits(t *testing.T) {
+	for contentType, limit := range attachmentTypes {
+		if limit > maxAttachmentSize {
+			t.Errorf("%s allows %d bytes, more than the %d of any attachment", contentType, limit, maxAttachmentSize)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Filename: cmd/api/bookmarks.go
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"universityforum.miguelavila.net/internals/data"
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+// bookmarkForumHandler for the "PUT /v1/forums/:id/bookmark" endpoint. The
+// body is optional and replaces the folder and note of an existing bookmark
+func (app *application) bookmarkForumHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	var input struct {
+		Folder string `json:"folder"`
+		Note   string `json:"note"`
+	}
+	if r.ContentLength != 0 {
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+	bookmark := &data.Bookmark{
+		Folder: strings.TrimSpace(input.Folder),
+		Note:   input.Note,
+	}
+	v := validator.New()
+	if data.ValidateBookmark(v, bookmark); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	// Make sure the forum exists
+	forum, err := app.models.Forum.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Bookmarks.Set(app.contextGetUser(r).ID, forum.ID, bookmark)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	forum.Bookmarked = true
+	forum.Bookmark = bookmark
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"forum": forum}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// unbookmarkForumHandler for the "DELETE /v1/forums/:id/bookmark" endpoint.
+// Removing a bookmark twice is a no-op
+func (app *application) unbookmarkForumHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	forum, err := app.models.Forum.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Bookmarks.Delete(app.contextGetUser(r).ID, forum.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	forum.Bookmarked = false
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"forum": forum}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listBookmarksHandler for the "GET /v1/users/me/bookmarks" endpoint. It is
+// paged and sorted like the forum listing, and can be narrowed to a folder
+func (app *application) listBookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Folder string
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	input.Folder = strings.TrimSpace(app.readString(qs, "folder", ""))
+	// Get the page information
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	// The most recently saved forums come first by default
+	input.Filters.Sort = app.readString(qs, "sort", "-bookmarked")
+	input.Filters.SortList = []string{"id", "title", "likes", "bookmarked", "-id", "-title", "-likes", "-bookmarked"}
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	forums, metadata, err := app.models.Bookmarks.GetAll(app.contextGetUser(r).ID, input.Folder, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.addForumReactions(forums, app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"forums": forums, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,78 @@
+// Filename: cmd/api/categories.go
+
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"universityforum.miguelavila.net/internals/data"
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+// createCategoryHandler for the "POST /v1/categories" endpoint
+func (app *application) createCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name     string `json:"name"`
+		ParentID int64  `json:"parent_id"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	category := &data.Category{
+		Name:     input.Name,
+		ParentID: input.ParentID,
+	}
+
+	v := validator.New()
+
+	// A sub category needs an existing parent
+	if category.ParentID != 0 {
+		_, err = app.models.Category.Get(category.ParentID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				v.AddError("parent_id", "must reference an existing category")
+			default:
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+	}
+	if data.ValidateCategory(v, category); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Category.Insert(category)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateCategory):
+			v.AddError("name", "a category with this name already exists here")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusCreated, envelope{"category": category}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listCategoriesHandler for the "GET /v1/categories" endpoint
+func (app *application) listCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	categories, err := app.models.Category.GetAll()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"categories": categories}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
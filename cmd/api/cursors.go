@@ -0,0 +1,81 @@
+// Filename: cmd/api/cursors.go
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+
+	"universityforum.miguelavila.net/internals/data"
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// cursorPayload is what a cursor token carries. The listing keeps the
+// cursor of one listing from being used with another
+type cursorPayload struct {
+	Listing string `json:"listing"`
+	data.Cursor
+}
+
+// readCursorParams() reads the "cursor" and "total" query parameters of a
+// listing into filters. A cursor replaces the page number; total=false
+// leaves out the count of the records
+func (app *application) readCursorParams(qs url.Values, listing string, filters *data.Filters, v *validator.Validator) {
+	if token := qs.Get("cursor"); token != "" {
+		cursor, err := app.decodeCursor(listing, token)
+		if err != nil {
+			v.AddError("cursor", "must be a cursor returned by this listing")
+		}
+		filters.Cursor = cursor
+		v.Check(qs.Get("page") == "", "page", "must not be used with a cursor")
+	}
+	total := app.readString(qs, "total", "true")
+	v.Check(validator.In(total, "true", "false"), "total", "must be true or false")
+	filters.NoTotal = total == "false"
+}
+
+// setNextCursor() turns the position after the page of a listing into the
+// next_cursor of its metadata
+func (app *application) setNextCursor(listing string, metadata *data.Metadata) {
+	if metadata.Next != nil {
+		metadata.NextCursor = app.encodeCursor(listing, metadata.Next)
+	}
+}
+
+// encodeCursor() makes an opaque token out of a cursor: its JSON followed by
+// an HMAC-SHA256 of the JSON, in URL safe base64. Clients cannot change the
+// position without invalidating the signature
+func (app *application) encodeCursor(listing string, cursor *data.Cursor) string {
+	// A cursor holds only strings, so it always marshals
+	payload, _ := json.Marshal(cursorPayload{Listing: listing, Cursor: *cursor})
+	mac := hmac.New(sha256.New, []byte(app.config.cursor.secret))
+	mac.Write(payload)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(payload))
+}
+
+// decodeCursor() checks the signature of a cursor token made by
+// encodeCursor() for the same listing and returns the cursor
+func (app *application) decodeCursor(listing, token string) (*data.Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) < sha256.Size {
+		return nil, errInvalidCursor
+	}
+	payload, signature := raw[:len(raw)-sha256.Size], raw[len(raw)-sha256.Size:]
+	mac := hmac.New(sha256.New, []byte(app.config.cursor.secret))
+	mac.Write(payload)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, errInvalidCursor
+	}
+	var cursor cursorPayload
+	err = json.Unmarshal(payload, &cursor)
+	if err != nil || cursor.Listing != listing {
+		return nil, errInvalidCursor
+	}
+	return &cursor.Cursor, nil
+}
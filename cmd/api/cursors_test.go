@@ -0,0 +1,109 @@
+// Filename: cmd/api/cursors_test.go
+
+package main
+
+import (
+	"encoding/base64"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"universityforum.miguelavila.net/internals/data"
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+func newCursorTestApp(secret string) *application {
+	app := &application{}
+	app.config.cursor.secret = secret
+	return app
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	app := newCursorTestApp("secret")
+	cursor := &data.Cursor{Sort: "-title", Keys: []string{"true", "Go, \"generics\"", "42"}}
+	token := app.encodeCursor("forums", cursor)
+
+	got, err := app.decodeCursor("forums", token)
+	if err != nil {
+		t.Fatalf("decoding: %v", err)
+	}
+	if !reflect.DeepEqual(got, cursor) {
+		t.Errorf("got %+v; want %+v", got, cursor)
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	app := newCursorTestApp("secret")
+	token := app.encodeCursor("forums", &data.Cursor{Sort: "id", Keys: []string{"false", "1"}})
+	raw, _ := base64.RawURLEncoding.DecodeString(token)
+
+	// Change the payload, keeping the signature
+	tampered := append([]byte{}, raw...)
+	tampered[len(tampered)-33]++
+	// Change the signature, keeping the payload
+	badSignature := append([]byte{}, raw...)
+	badSignature[len(badSignature)-1]++
+
+	tests := []struct {
+		name    string
+		app     *application
+		listing string
+		token   string
+	}{
+		{"empty", app, "forums", ""},
+		{"not base64", app, "forums", "not a cursor!"},
+		{"too short", app, "forums", base64.RawURLEncoding.EncodeToString([]byte("short"))},
+		{"tampered payload", app, "forums", base64.RawURLEncoding.EncodeToString(tampered)},
+		{"bad signature", app, "forums", base64.RawURLEncoding.EncodeToString(badSignature)},
+		{"other secret", newCursorTestApp("other"), "forums", token},
+		{"other listing", app, "replies", token},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, err := tt.app.decodeCursor(tt.listing, tt.token)
+			if err != errInvalidCursor {
+				t.Errorf("got %+v, %v; want errInvalidCursor", cursor, err)
+			}
+		})
+	}
+}
+
+func TestReadCursorParams(t *testing.T) {
+	app := newCursorTestApp("secret")
+	token := app.encodeCursor("forums", &data.Cursor{Sort: "id", Keys: []string{"false", "1"}})
+	tests := []struct {
+		name      string
+		query     url.Values
+		errorKeys []string
+		cursor    bool
+		noTotal   bool
+	}{
+		{"nothing", url.Values{}, nil, false, false},
+		{"cursor", url.Values{"cursor": {token}}, nil, true, false},
+		{"cursor without total", url.Values{"cursor": {token}, "total": {"false"}}, nil, true, true},
+		{"cursor and page", url.Values{"cursor": {token}, "page": {"2"}}, []string{"page"}, true, false},
+		{"cursor of another listing", url.Values{"cursor": {app.encodeCursor("replies", &data.Cursor{Sort: "id"})}}, []string{"cursor"}, false, false},
+		{"bad total", url.Values{"total": {"maybe"}}, []string{"total"}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var filters data.Filters
+			v := validator.New()
+			app.readCursorParams(tt.query, "forums", &filters, v)
+			for _, key := range tt.errorKeys {
+				if _, ok := v.Errors[key]; !ok {
+					t.Errorf("got errors %v; want one for %q", v.Errors, key)
+				}
+			}
+			if len(v.Errors) != len(tt.errorKeys) {
+				t.Errorf("got errors %v; want %v", v.Errors, tt.errorKeys)
+			}
+			if (filters.Cursor != nil) != tt.cursor {
+				t.Errorf("got cursor %+v; want one: %t", filters.Cursor, tt.cursor)
+			}
+			if filters.NoTotal != tt.noTotal {
+				t.Errorf("got NoTotal %t; want %t", filters.NoTotal, tt.noTotal)
+			}
+		})
+	}
+}
@@ -117,6 +117,42 @@ func (app *application) notPermittedResponse(w http.ResponseWriter, r *http.Requ
 	app.errorResponse(w, r, http.StatusForbidden, message)
 }
 
+// Forum is locked against new replies
+func (app *application) forumLockedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "this forum is locked and does not accept new replies"
+	app.errorResponse(w, r, http.StatusLocked, message)
+}
+
+// Only question forums have accepted answers
+func (app *application) notQuestionResponse(w http.ResponseWriter, r *http.Request) {
+	message := "only replies to question forums can be accepted as answers"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+// Only the accepted answer can be unaccepted
+func (app *application) notAcceptedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "this reply is not the accepted answer"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+// User has already voted on the poll
+func (app *application) alreadyVotedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "you have already voted on this poll"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+// Poll no longer accepts votes
+func (app *application) pollClosedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "this poll is closed"
+	app.errorResponse(w, r, http.StatusConflict, message)
+}
+
+// Uploaded file too large
+func (app *application) fileTooLargeResponse(w http.ResponseWriter, r *http.Request, limit int64) {
+	message := fmt.Sprintf("the uploaded file must not exceed %d bytes", limit)
+	app.errorResponse(w, r, http.StatusRequestEntityTooLarge, message)
+}
+
 // User provided a bad request
 func (app *application) badRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
 	app.errorResponse(w, r, http.StatusBadRequest, err.Error())
@@ -0,0 +1,126 @@
+// Filename: cmd/api/events.go
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"universityforum.miguelavila.net/internals/data"
+)
+
+const (
+	// How often a comment is sent so proxies keep idle streams open
+	eventHeartbeat = 15 * time.Second
+	// How long a single write to a stream may take. The server WriteTimeout
+	// would otherwise end every stream after 30 seconds
+	eventWriteTimeout = 10 * time.Second
+	// How many events are buffered for a slow client before it is dropped
+	eventBufferSize = 32
+)
+
+// forumTopic() is the topic of the live updates of a forum
+func forumTopic(forumID int64) string {
+	return fmt.Sprintf("forum:%d", forumID)
+}
+
+// userTopic() is the topic of the notifications of a user
+func userTopic(userID int64) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// publish() sends an event to the clients streaming topic
+func (app *application) publish(topic, eventType string, payload envelope) {
+	err := app.events.Publish(topic, eventType, payload)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{"topic": topic, "event": eventType})
+	}
+}
+
+// forumEventsHandler for the "GET /v1/forums/:id/events" endpoint. It streams
+// new replies, edits and likes of the forum
+func (app *application) forumEventsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	// Make sure the forum exists
+	_, err = app.models.Forum.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.streamEvents(w, r, forumTopic(id))
+}
+
+// notificationEventsHandler for the "GET /v1/notifications/events" endpoint.
+// It streams the new notifications of the current user
+func (app *application) notificationEventsHandler(w http.ResponseWriter, r *http.Request) {
+	app.streamEvents(w, r, userTopic(app.contextGetUser(r).ID))
+}
+
+// streamEvents() writes the events of topic to the client as server-sent
+// events until the client goes away or the server shuts down
+func (app *application) streamEvents(w http.ResponseWriter, r *http.Request, topic string) {
+	rc := http.NewResponseController(w)
+	sub := app.events.Subscribe(topic)
+	defer sub.Close()
+
+	// write() gives every write its own deadline so that a stream can outlive
+	// the server WriteTimeout while a stuck client is still cut off
+	write := func(format string, args ...interface{}) error {
+		err := rc.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, format, args...)
+		if err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Stop nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	err := write("retry: %d\n\n", (5 * time.Second).Milliseconds())
+	if err != nil {
+		app.logError(r, err)
+		return
+	}
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.C:
+			// The broker is closed on shutdown, and slow clients are
+			// dropped. Either way the client reconnects
+			if !ok {
+				if sub.Lagged() {
+					app.logger.PrintInfo("dropped slow event stream", map[string]string{"topic": topic})
+				}
+				return
+			}
+			err = write("id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+		case <-heartbeat.C:
+			err = write(": heartbeat\n\n")
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"universityforum.miguelavila.net/internals/data"
 	"universityforum.miguelavila.net/internals/validator"
@@ -13,8 +14,24 @@ import (
 func (app *application) createForumHandler(w http.ResponseWriter, r *http.Request) {
 	// Our target decode destination
 	var input struct {
-		Title       string `json:"title"`
-		Description string `json:"description"`
+		Title       string   `json:"title"`
+		Description string   `json:"description"`
+		Tags        []string `json:"tags"`
+		CategoryID  int64    `json:"category_id"`
+		Kind        string   `json:"kind"`
+		// Forums are published straight away unless they are saved as a
+		// draft or scheduled for publish_at
+		Status    string     `json:"status"`
+		PublishAt *time.Time `json:"publish_at"`
+		// An optional poll carried by the forum
+		Poll *struct {
+			Question       string     `json:"question"`
+			Options        []string   `json:"options"`
+			MultipleChoice bool       `json:"multiple_choice"`
+			Anonymous      bool       `json:"anonymous"`
+			HideResults    bool       `json:"hide_results"`
+			ClosesAt       *time.Time `json:"closes_at"`
+		} `json:"poll"`
 	}
 	// Initialize a new json.Decoder instance
 	err := app.readJSON(w, r, &input)
@@ -23,14 +40,66 @@ func (app *application) createForumHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// The current user becomes the author of the forum
+	user := app.contextGetUser(r)
 	// Copy the values from the input struct to a new Forum struct
 	forum := &data.Forum{
 		Title:       input.Title,
 		Description: input.Description,
+		AuthorID:    user.ID,
+		Author:      &data.UserSummary{ID: user.ID, Name: user.Name},
+		Tags:        data.NormalizeTags(input.Tags),
+		Kind:        input.Kind,
+		Status:      input.Status,
+	}
+	if forum.Kind == "" {
+		forum.Kind = data.ForumKindDiscussion
+	}
+	if forum.Status == "" {
+		forum.Status = data.ForumStatusPublished
+	}
+	// The publish time only matters to a scheduled forum
+	if forum.Status == data.ForumStatusScheduled {
+		forum.PublishAt = input.PublishAt
+	}
+	// Only moderators may post announcements
+	if forum.Kind == data.ForumKindAnnouncement {
+		permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		if !permissions.Include("forums:moderate") {
+			app.notPermittedResponse(w, r)
+			return
+		}
+	}
+	if input.Poll != nil {
+		forum.Poll = &data.Poll{
+			Question:       input.Poll.Question,
+			MultipleChoice: input.Poll.MultipleChoice,
+			Anonymous:      input.Poll.Anonymous,
+			HideResults:    input.Poll.HideResults,
+			ClosesAt:       input.Poll.ClosesAt,
+		}
+		for _, text := range input.Poll.Options {
+			forum.Poll.Options = append(forum.Poll.Options, &data.PollOption{Text: text})
+		}
 	}
 
 	// Initialize a new Validator instance
 	v := validator.New()
+	v.Check(forum.PublishAt == nil || forum.PublishAt.After(time.Now()), "publish_at", "must be in the future")
+
+	// Check that the category exists
+	if input.CategoryID != 0 {
+		forum.Category, err = app.readForumCategory(v, input.CategoryID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		forum.CategoryID = input.CategoryID
+	}
 
 	// Check the map to determine if there were any validation errors
 	if data.ValidateForum(v, forum); !v.Valid() {
@@ -42,6 +111,13 @@ func (app *application) createForumHandler(w http.ResponseWriter, r *http.Reques
 	err = app.models.Forum.Insert(forum)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// The author follows their forum
+	forum.Subscribed = true
+	// Let the users mentioned know, once everyone can see the forum
+	if forum.Status == data.ForumStatusPublished {
+		app.notifyMentionedInForum(forum, app.contextGetUser(r))
 	}
 	// Create a Location header for the newly created resource/Forum
 	headers := make(http.Header)
@@ -63,7 +139,7 @@ func (app *application) showForumHandler(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Fetch the specific forum
-	forum, err := app.models.Forum.Get(id)
+	forum, err := app.models.Forum.GetVisible(id, app.contextGetUser(r).ID)
 	// Handle errors
 	if err != nil {
 		switch {
@@ -74,6 +150,36 @@ func (app *application) showForumHandler(w http.ResponseWriter, r *http.Request)
 		}
 		return
 	}
+	// Check whether the current user likes the forum
+	forum.LikedByMe, err = app.models.Likes.Exists(app.contextGetUser(r).ID, forum.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// Check whether the current user follows the forum
+	forum.Subscribed, err = app.models.Subscriptions.Exists(app.contextGetUser(r).ID, forum.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// Check whether the current user saved the forum
+	forum.Bookmarked, err = app.models.Bookmarks.Exists(app.contextGetUser(r).ID, forum.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// Embed the reactions to the forum
+	err = app.addForumReactions([]*data.Forum{forum}, app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// Embed the poll and its results, if the forum has one
+	forum.Poll, err = app.models.Polls.Get(forum.ID, app.contextGetUser(r).ID)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 
 	err = app.writeJSON(w, http.StatusOK, envelope{"forum": forum}, nil)
 	if err != nil {
@@ -91,7 +197,7 @@ func (app *application) updateForumHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 	// Fetch the orginal record from the database
-	forum, err := app.models.Forum.Get(id)
+	forum, err := app.models.Forum.GetVisible(id, app.contextGetUser(r).ID)
 	// Handle errors
 	if err != nil {
 		switch {
@@ -102,13 +208,28 @@ func (app *application) updateForumHandler(w http.ResponseWriter, r *http.Reques
 		}
 		return
 	}
+	// Only the author or a moderator may edit the forum
+	allowed, err := app.isOwnerOrModerator(app.contextGetUser(r), forum.AuthorID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		app.notPermittedResponse(w, r)
+		return
+	}
 	// Create an input struct to hold data read in from the client
 	// We update input struct to use pointers because pointers have a
 	// default value of nil
 	// If a field remains nil then we know that the client did not update it
 	var input struct {
-		Title       *string `json:"title"`
-		Description *string `json:"description"`
+		Title       *string  `json:"title"`
+		Description *string  `json:"description"`
+		Tags        []string `json:"tags"`
+		CategoryID  *int64   `json:"category_id"`
+		// Publishing a forum is done by setting its status to published
+		Status    *string    `json:"status"`
+		PublishAt *time.Time `json:"publish_at"`
 	}
 
 	// Initialize a new json.Decoder instance
@@ -125,19 +246,51 @@ func (app *application) updateForumHandler(w http.ResponseWriter, r *http.Reques
 	if input.Description != nil {
 		forum.Description = *input.Description
 	}
+	if input.Tags != nil {
+		forum.Tags = data.NormalizeTags(input.Tags)
+	}
 
 	// Perform validation on the updated Description. If validation fails, then
 	// we send a 422 - Unprocessable Entity respose to the client
 	// Initialize a new Validator instance
 	v := validator.New()
 
+	wasPublished := forum.Status == data.ForumStatusPublished
+	if input.Status != nil {
+		v.Check(!wasPublished || *input.Status == data.ForumStatusPublished, "status", "a published forum cannot be unpublished")
+		forum.Status = *input.Status
+	}
+	if input.PublishAt != nil {
+		v.Check(input.PublishAt.After(time.Now()), "publish_at", "must be in the future")
+		forum.PublishAt = input.PublishAt
+	}
+	// The publish time only matters to a scheduled forum
+	if forum.Status != data.ForumStatusScheduled {
+		forum.PublishAt = nil
+	}
+
+	// A category_id of 0 removes the forum from its category
+	if input.CategoryID != nil {
+		forum.CategoryID = *input.CategoryID
+		forum.Category = nil
+		if forum.CategoryID != 0 {
+			forum.Category, err = app.readForumCategory(v, forum.CategoryID)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
+	}
+
 	// Check the map to determine if there were any validation errors
 	if data.ValidateForum(v, forum); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
-	// Pass the updated Forum record to the Update() method
-	err = app.models.Forum.Update(forum)
+	// Pass the updated Forum record to the Update() method. A forum that
+	// stays hidden gets its new status in the same write, unless the
+	// scheduler published it in the meantime
+	err = app.models.Forum.Update(forum, app.contextGetUser(r).ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrEditConflict):
@@ -147,6 +300,19 @@ func (app *application) updateForumHandler(w http.ResponseWriter, r *http.Reques
 		}
 		return
 	}
+	switch {
+	case wasPublished:
+		// Let the users newly mentioned know
+		app.notifyMentionedInForum(forum, app.contextGetUser(r))
+		app.publish(forumTopic(forum.ID), "forum.updated", envelope{"forum": forum})
+	case forum.Status == data.ForumStatusPublished:
+		// Publish the forum with its new content
+		err = app.publishForum(forum, app.contextGetUser(r))
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
 	// Write the data returned by Get()
 	err = app.writeJSON(w, http.StatusOK, envelope{"forum": forum}, nil)
 	if err != nil {
@@ -162,9 +328,30 @@ func (app *application) deleteForumHandler(w http.ResponseWriter, r *http.Reques
 		app.notFoundResponse(w, r)
 		return
 	}
-	// Delete the Forum from the database. Send a 404 Not Found status code to the
+	// Fetch the forum so we can check who owns it
+	forum, err := app.models.Forum.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	// Only the author or a moderator may delete the forum
+	allowed, err := app.isOwnerOrModerator(app.contextGetUser(r), forum.AuthorID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		app.notPermittedResponse(w, r)
+		return
+	}
+	// Move the Forum to the trash. Send a 404 Not Found status code to the
 	// client if there is no matching record
-	err = app.models.Forum.Delete(id)
+	err = app.models.Forum.Delete(forum.ID, app.contextGetUser(r).ID)
 	// Handle errors
 	if err != nil {
 		switch {
@@ -175,6 +362,8 @@ func (app *application) deleteForumHandler(w http.ResponseWriter, r *http.Reques
 		}
 		return
 	}
+	// Tell the author when a moderator deleted their forum
+	app.notifyModeration(forum, app.contextGetUser(r), "deleted")
 	// Return 200 Status OK to the client with a success message
 	err = app.writeJSON(w, http.StatusOK, envelope{"message": "forum successfully deleted"}, nil)
 	if err != nil {
@@ -187,7 +376,7 @@ func (app *application) deleteForumHandler(w http.ResponseWriter, r *http.Reques
 func (app *application) listForumsHandler(w http.ResponseWriter, r *http.Request) {
 	// Create an input struct to hold our query parameters
 	var input struct {
-		Title string
+		data.ForumCriteria
 		data.Filters
 	}
 	// Initialize a validator
@@ -196,26 +385,62 @@ func (app *application) listForumsHandler(w http.ResponseWriter, r *http.Request
 	qs := r.URL.Query()
 	// Use the helper methods to extract the values
 	input.Title = app.readString(qs, "title", "")
+	// q searches the titles, descriptions and replies
+	input.Search = app.readString(qs, "q", "")
+	input.Tags = qs["tag"]
+	tagMode := app.readString(qs, "tag_mode", "all")
+	input.AnyTag = tagMode == "any"
+	input.CategoryID = int64(app.readInt(qs, "category", 0, v))
+	input.AuthorID = int64(app.readInt(qs, "author", 0, v))
+	// Only question forums are answered or not
+	answered := app.readString(qs, "answered", "")
+	v.Check(validator.In(answered, "", "true", "false"), "answered", "must be true or false")
+	if answered != "" {
+		isAnswered := answered == "true"
+		input.Answered = &isAnswered
+	}
+	// filter=unread lists the forums with something new to the user
+	filter := app.readString(qs, "filter", "")
+	v.Check(validator.In(filter, "", "unread"), "filter", "must be unread")
+	input.Unread = filter == "unread"
+	// Authors list their own drafts and scheduled forums by status
+	input.Status = app.readString(qs, "status", data.ForumStatusPublished)
+	v.Check(validator.In(input.Status, data.ForumStatusDraft, data.ForumStatusScheduled, data.ForumStatusPublished), "status", "must be draft, scheduled or published")
 	//input.Message = app.readString(qs, "message", "")
 	// Get the page information
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	// Get the sort information
-	input.Filters.Sort = app.readString(qs, "sort", "id")
+	// Infinite scrolling continues from the next_cursor of the last page
+	app.readCursorParams(qs, "forums", &input.Filters, v)
+	// Get the sort information. Search results come best match first, after
+	// the pinned forums
+	defaultSort := "id"
+	if input.Search != "" {
+		defaultSort = "relevance"
+	}
+	input.Filters.Sort = app.readString(qs, "sort", defaultSort)
 	// Specific the allowed sort values
-	input.Filters.SortList = []string{"id", "title", "-id", "-title"}
+	input.Filters.SortList = []string{"id", "title", "likes", "relevance", "-id", "-title", "-likes"}
+	v.Check(input.Filters.Sort != "relevance" || input.Search != "", "sort", "relevance needs a search query q")
 	// Check for validation errors
-	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+	v.Check(validator.In(tagMode, "all", "any"), "tag_mode", "must be all or any")
+	if data.ValidateForumFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
 	// Get a listing of all forums
-	forums, metadata, err := app.models.Forum.GetAll(input.Title, input.Filters)
+	forums, metadata, err := app.models.Forum.GetAll(input.ForumCriteria, app.contextGetUser(r).ID, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.addForumReactions(forums, app.contextGetUser(r).ID)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
+	app.setNextCursor("forums", &metadata)
 	// Send a JSON response containg all the forums
 	err = app.writeJSON(w, http.StatusOK, envelope{"forums": forums, "metadata": metadata}, nil)
 	if err != nil {
@@ -223,3 +448,19 @@ func (app *application) listForumsHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 }
+
+// readForumCategory() fetches the category a forum is being filed under. A
+// validation error is recorded if the category does not exist
+func (app *application) readForumCategory(v *validator.Validator, id int64) (*data.Category, error) {
+	category, err := app.models.Category.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("category_id", "must reference an existing category")
+			return nil, nil
+		default:
+			return nil, err
+		}
+	}
+	return category, nil
+}
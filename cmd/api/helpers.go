@@ -13,6 +13,7 @@ import (
 	"strings"
 
 	"github.com/julienschmidt/httprouter"
+	"universityforum.miguelavila.net/internals/data"
 	"universityforum.miguelavila.net/internals/validator"
 )
 
@@ -130,6 +131,16 @@ func (app *application) readIDParam(r *http.Request) (int64, error) {
 	return id, nil
 }
 
+// The readVersionParam() method returns the ":version" parameter of the URL
+func (app *application) readVersionParam(r *http.Request) (int32, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+	version, err := strconv.ParseInt(params.ByName("version"), 10, 32)
+	if err != nil || version < 1 {
+		return 0, errors.New("invalid version parameter")
+	}
+	return int32(version), nil
+}
+
 // The readString() method returns a string value from the query parameter
 // string or returns a default value if no matching key is found
 func (app *application) readString(qs url.Values, key string, defaultValue string) string {
@@ -158,3 +169,28 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int, v *
 	}
 	return intValue
 }
+
+// The isOwnerOrModerator() method reports whether the user is the owner of a
+// resource or holds the "forums:moderate" permission
+func (app *application) isOwnerOrModerator(user *data.User, ownerID int64) (bool, error) {
+	if !user.IsAnonymous() && user.ID == ownerID {
+		return true, nil
+	}
+	permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+	if err != nil {
+		return false, err
+	}
+	return permissions.Include("forums:moderate"), nil
+}
+
+// The longest excerpt of a text sent in a notification
+const excerptLength = 280
+
+// excerpt() shortens a text for a notification
+func excerpt(text string) string {
+	runes := []rune(text)
+	if len(runes) <= excerptLength {
+		return text
+	}
+	return string(runes[:excerptLength]) + "…"
+}
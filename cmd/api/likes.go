@@ -0,0 +1,72 @@
+// Filename: cmd/api/likes.go
+
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"universityforum.miguelavila.net/internals/data"
+)
+
+// likeForumHandler for the "PUT /v1/forums/:id/like" endpoint
+func (app *application) likeForumHandler(w http.ResponseWriter, r *http.Request) {
+	app.setForumLike(w, r, true)
+}
+
+// unlikeForumHandler for the "DELETE /v1/forums/:id/like" endpoint
+func (app *application) unlikeForumHandler(w http.ResponseWriter, r *http.Request) {
+	app.setForumLike(w, r, false)
+}
+
+// setForumLike() adds or removes the like of the current user and writes the
+// updated forum back to the client. Both operations are idempotent
+func (app *application) setForumLike(w http.ResponseWriter, r *http.Request, liked bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	// Make sure the forum exists
+	forum, err := app.models.Forum.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user := app.contextGetUser(r)
+	if liked {
+		var added bool
+		added, err = app.models.Likes.Insert(user.ID, id)
+		// Only a new like is worth telling the author about
+		if err == nil && added {
+			app.notify([]int64{forum.AuthorID}, data.NotificationLike, user, envelope{"forum_id": forum.ID})
+		}
+	} else {
+		err = app.models.Likes.Delete(user.ID, id)
+	}
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Fetch the forum again so the client gets the new like count
+	forum, err = app.models.Forum.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	forum.LikedByMe = liked
+	// Everyone watching the forum only needs the new count
+	app.publish(forumTopic(forum.ID), "forum.liked", envelope{"forum_id": forum.ID, "like_count": forum.LikeCount})
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"forum": forum}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -3,6 +3,7 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
 	"flag"
 	"os"
@@ -12,8 +13,10 @@ import (
 
 	_ "github.com/lib/pq"
 	"universityforum.miguelavila.net/internals/data"
+	"universityforum.miguelavila.net/internals/events"
 	"universityforum.miguelavila.net/internals/jsonlog"
 	"universityforum.miguelavila.net/internals/mailer"
+	"universityforum.miguelavila.net/internals/storage"
 )
 
 // App Version
@@ -40,19 +43,40 @@ type config struct {
 		username string
 		password string
 		sender   string
+		// how many mails are sent over a single connection
+		batchSize int
 	}
 	cors struct {
 		trustedOrigin []string
 	}
+	trash struct {
+		retention time.Duration // how long deleted forums are kept
+	}
+	storage struct {
+		dir string // where uploaded files are kept
+	}
+	cursor struct {
+		secret string // signs the pagination cursors
+	}
+	// the emojis users can react with, in display order
+	reactions []string
 }
 
 // dependencies injections
 type application struct {
-	config config
-	logger *jsonlog.Logger
-	models data.Models
-	mailer mailer.Mailer
-	wg     sync.WaitGroup
+	config  config
+	logger  *jsonlog.Logger
+	models  data.Models
+	mailer  mailer.Mailer
+	storage storage.Storage
+	// events fans live updates out to the clients streaming them
+	events *events.Broker
+	// suggestions caches the forum title suggestions
+	suggestions *suggestionCache
+	wg          sync.WaitGroup
+	// done is closed when the server starts shutting down so that
+	// long running background jobs can stop
+	done chan struct{}
 }
 
 func main() {
@@ -76,6 +100,23 @@ func main() {
 	flag.StringVar(&cfg.stmp.username, "stmp-username", os.Getenv("STMP_USERNAME"), "STMP server username")
 	flag.StringVar(&cfg.stmp.password, "stmp-password", os.Getenv("STMP_PASSWORD"), "STMP server password")
 	flag.StringVar(&cfg.stmp.sender, "stmp-sender", "GobalUniversiryForum <no-reply@universityforum.forums.net>", "STMP server sender")
+	flag.IntVar(&cfg.stmp.batchSize, "stmp-batch-size", 50, "STMP mails sent per connection when notifying many users")
+
+	// Flag for the forum trash
+	flag.DurationVar(&cfg.trash.retention, "trash-retention", 30*24*time.Hour, "How long deleted forums are kept before they are purged")
+
+	// Flag for uploaded files
+	flag.StringVar(&cfg.storage.dir, "storage-dir", "./uploads", "Directory for uploaded files")
+
+	// Flag for the emoji reactions
+	cfg.reactions = []string{"👍", "🎉", "❓", "👀"}
+	flag.Func("reactions", "Emojis users can react with (space separated, default \"👍 🎉 ❓ 👀\")", func(val string) error {
+		cfg.reactions = strings.Fields(val)
+		return nil
+	})
+
+	// Flag for the pagination cursors
+	flag.StringVar(&cfg.cursor.secret, "cursor-secret", os.Getenv("FORUM_CURSOR_SECRET"), "Secret signing the pagination cursors")
 
 	// use flag.Func() function to parse our trusted Origins flags from
 	flag.Func("cors-trusted-origins", "Trusted CORS origin (space separated)", func(val string) error {
@@ -99,14 +140,42 @@ func main() {
 	// log successful connection
 	logger.PrintInfo("database connection pool established edited", nil)
 
+	// Without a configured secret the cursors handed out stop working when
+	// the server restarts
+	if cfg.cursor.secret == "" {
+		secret := make([]byte, 32)
+		_, err = rand.Read(secret)
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
+		cfg.cursor.secret = string(secret)
+		logger.PrintInfo("no cursor secret set, using a random one", nil)
+	}
+
+	// open the storage for uploaded files
+	files, err := storage.NewLocal(cfg.storage.dir)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
 	//create instances of out api
 	app := &application{
-		config: cfg,
-		logger: logger,
-		models: *data.NewModels(db),
-		mailer: mailer.New(cfg.stmp.host, cfg.stmp.port, cfg.stmp.username, cfg.stmp.password, cfg.stmp.sender),
+		config:      cfg,
+		logger:      logger,
+		models:      *data.NewModels(db),
+		mailer:      mailer.New(cfg.stmp.host, cfg.stmp.port, cfg.stmp.username, cfg.stmp.password, cfg.stmp.sender),
+		storage:     files,
+		events:      events.NewBroker(eventBufferSize),
+		done:        make(chan struct{}),
+		suggestions: newSuggestionCache(),
 	}
 
+	// Start the background jobs
+	app.background(app.purgeTrash)
+	app.background(app.publishScheduledForums)
+	app.background(app.refreshSearchVectors)
+	app.background(app.removeDeletedAttachments)
+
 	// Call app.serve() to start the server
 	err = app.serve()
 	if err != nil {
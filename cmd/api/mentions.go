@@ -0,0 +1,69 @@
+// Filename: cmd/api/mentions.go
+
+package main
+
+import (
+	"fmt"
+
+	"universityforum.miguelavila.net/internals/data"
+	"universityforum.miguelavila.net/internals/mailer"
+)
+
+// notifyMentionedInForum() tells the users mentioned for the first time in
+// a forum description about it
+func (app *application) notifyMentionedInForum(forum *data.Forum, author *data.User) {
+	payload := envelope{"forum_id": forum.ID}
+	app.notifyMentioned(forum.Mentioned, author, "forum", fmt.Sprintf("/v1/forums/%d", forum.ID), forum.Description, payload)
+}
+
+// notifyMentionedInReply() tells the users mentioned for the first time in a
+// reply about it
+func (app *application) notifyMentionedInReply(reply *data.Reply, author *data.User) {
+	payload := envelope{"forum_id": reply.ForumID, "reply_id": reply.ID}
+	app.notifyMentioned(reply.Mentioned, author, "reply", fmt.Sprintf("/v1/replies/%d", reply.ID), reply.Message, payload)
+}
+
+// notifyMentioned() adds a mention to the inbox of the mentioned users and
+// mails them in the background, in batches like notifySubscribers()
+func (app *application) notifyMentioned(users []*data.User, author *data.User, kind, path, text string, payload envelope) {
+	if len(users) == 0 {
+		return
+	}
+	excerpt := excerpt(text)
+
+	ids := make([]int64, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	payload["excerpt"] = excerpt
+	app.notify(ids, data.NotificationMention, author, payload)
+
+	app.background(func() {
+		batch := []mailer.Message{}
+		send := func() {
+			err := app.mailer.SendBatch("user_mentioned.tmpl", batch)
+			if err != nil {
+				app.logger.PrintError(err, map[string]string{"path": path})
+			}
+			batch = batch[:0]
+		}
+		for _, user := range users {
+			batch = append(batch, mailer.Message{
+				Recipient: user.Email,
+				Data: map[string]interface{}{
+					"name":       user.Name,
+					"authorName": author.Name,
+					"kind":       kind,
+					"path":       path,
+					"excerpt":    excerpt,
+				},
+			})
+			if len(batch) >= app.config.stmp.batchSize {
+				send()
+			}
+		}
+		if len(batch) > 0 {
+			send()
+		}
+	})
+}
@@ -0,0 +1,100 @@
+// Filename: cmd/api/moderation.go
+
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"universityforum.miguelavila.net/internals/data"
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+// pinForumHandler for the "PUT /v1/forums/:id/pin" endpoint
+func (app *application) pinForumHandler(w http.ResponseWriter, r *http.Request) {
+	app.setForumState(w, r, "pinned", func(forum *data.Forum) { forum.Pinned = true })
+}
+
+// unpinForumHandler for the "DELETE /v1/forums/:id/pin" endpoint
+func (app *application) unpinForumHandler(w http.ResponseWriter, r *http.Request) {
+	app.setForumState(w, r, "unpinned", func(forum *data.Forum) { forum.Pinned = false })
+}
+
+// lockForumHandler for the "PUT /v1/forums/:id/lock" endpoint
+func (app *application) lockForumHandler(w http.ResponseWriter, r *http.Request) {
+	app.setForumState(w, r, "locked", func(forum *data.Forum) { forum.Locked = true })
+}
+
+// unlockForumHandler for the "DELETE /v1/forums/:id/lock" endpoint
+func (app *application) unlockForumHandler(w http.ResponseWriter, r *http.Request) {
+	app.setForumState(w, r, "unlocked", func(forum *data.Forum) { forum.Locked = false })
+}
+
+// updateForumKindHandler for the "PUT /v1/forums/:id/kind" endpoint
+func (app *application) updateForumKindHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Kind string `json:"kind"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	v.Check(validator.In(input.Kind, data.ForumKindDiscussion, data.ForumKindQuestion, data.ForumKindAnnouncement), "kind", "must be discussion, question or announcement")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	app.setForumState(w, r, "kind_changed", func(forum *data.Forum) { forum.Kind = input.Kind })
+}
+
+// setForumState() applies a change to the moderation settings of a forum and
+// writes the updated forum back to the client. The changes are idempotent;
+// the author is told about the action when it changed something
+func (app *application) setForumState(w http.ResponseWriter, r *http.Request, action string, change func(forum *data.Forum)) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	forum, err := app.models.Forum.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	pinned, locked, kind := forum.Pinned, forum.Locked, forum.Kind
+	change(forum)
+	err = app.models.Forum.UpdateState(forum)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if pinned != forum.Pinned || locked != forum.Locked || kind != forum.Kind {
+		app.notifyModeration(forum, app.contextGetUser(r), action)
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"forum": forum}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// notifyModeration() tells the author of a forum that a moderator acted on it
+func (app *application) notifyModeration(forum *data.Forum, moderator *data.User, action string) {
+	app.notify([]int64{forum.AuthorID}, data.NotificationModeration, moderator, envelope{
+		"forum_id": forum.ID,
+		"title":    forum.Title,
+		"action":   action,
+	})
+}
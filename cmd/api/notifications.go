@@ -0,0 +1,123 @@
+// Filename: cmd/api/notifications.go
+
+package main
+
+import (
+	"net/http"
+
+	"universityforum.miguelavila.net/internals/data"
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+// listNotificationsHandler for the "GET /v1/notifications" endpoint
+func (app *application) listNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Unread string
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	input.Unread = app.readString(qs, "unread", "false")
+	// Get the page information
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	// The newest notifications come first by default
+	input.Filters.Sort = app.readString(qs, "sort", "-id")
+	input.Filters.SortList = []string{"id", "-id"}
+	v.Check(validator.In(input.Unread, "true", "false"), "unread", "must be true or false")
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	notifications, metadata, err := app.models.Notifications.GetAll(app.contextGetUser(r).ID, input.Unread == "true", input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"notifications": notifications, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// markNotificationsReadHandler for the "POST /v1/notifications/read"
+// endpoint. The client sends either the ids to mark or "all": true
+func (app *application) markNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		IDs []int64 `json:"ids"`
+		All bool    `json:"all"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	v.Check(input.All || len(input.IDs) > 0, "ids", "must be provided unless all is set")
+	v.Check(!input.All || len(input.IDs) == 0, "ids", "must not be provided when all is set")
+	v.Check(len(input.IDs) <= 100, "ids", "must not contain more than 100 ids")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	marked, err := app.models.Notifications.MarkRead(user.ID, input.IDs, input.All)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	unread, err := app.models.Notifications.CountUnread(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"marked": marked, "unread_count": unread}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// unreadNotificationsCountHandler for the "GET /v1/notifications/unread-count"
+// endpoint
+func (app *application) unreadNotificationsCountHandler(w http.ResponseWriter, r *http.Request) {
+	unread, err := app.models.Notifications.CountUnread(app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"unread_count": unread}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// notify() adds a notification to the inbox of the users in the background.
+// The actor is added to the payload and is never notified of their own
+// actions. A nil actor means the server acted on its own, as the scheduler does
+func (app *application) notify(userIDs []int64, notificationType string, actor *data.User, payload envelope) {
+	recipients := []int64{}
+	for _, id := range userIDs {
+		if id != 0 && (actor == nil || id != actor.ID) {
+			recipients = append(recipients, id)
+		}
+	}
+	if len(recipients) == 0 {
+		return
+	}
+	if actor != nil {
+		payload["actor"] = data.UserSummary{ID: actor.ID, Name: actor.Name}
+	}
+	app.background(func() {
+		notifications, err := app.models.Notifications.Insert(recipients, notificationType, payload)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{"type": notificationType})
+			return
+		}
+		// Push them to the users who have a stream open
+		for userID, notification := range notifications {
+			app.publish(userTopic(userID), "notification", envelope{"notification": notification})
+		}
+	})
+}
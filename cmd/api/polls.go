@@ -0,0 +1,86 @@
+// Filename: cmd/api/polls.go
+
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"universityforum.miguelavila.net/internals/data"
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+// votePollHandler for the "POST /v1/forums/:id/poll/votes" endpoint
+func (app *application) votePollHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	var input struct {
+		OptionIDs []int64 `json:"option_ids"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// The forum must be visible and carry a poll
+	user := app.contextGetUser(r)
+	_, err = app.models.Forum.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	poll, err := app.models.Polls.Get(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if poll.Closed {
+		app.pollClosedResponse(w, r)
+		return
+	}
+	v := validator.New()
+	if data.ValidateVote(v, poll, input.OptionIDs); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Polls.Vote(id, user.ID, input.OptionIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		case errors.Is(err, data.ErrPollClosed):
+			app.pollClosedResponse(w, r)
+		case errors.Is(err, data.ErrAlreadyVoted):
+			app.alreadyVotedResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Send back the poll as it stands after the vote
+	poll, err = app.models.Polls.Get(id, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusCreated, envelope{"poll": poll}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
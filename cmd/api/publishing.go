@@ -0,0 +1,78 @@
+// Filename: cmd/api/publishing.go
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"universityforum.miguelavila.net/internals/data"
+)
+
+// How often the scheduler looks for forums that are due
+const publishInterval = time.Minute
+
+// publishForum() publishes a draft or scheduled forum and announces it. The
+// actor is the user publishing it, or nil when the scheduler does. A forum
+// that someone else published first is not announced again
+func (app *application) publishForum(forum *data.Forum, actor *data.User) error {
+	published, err := app.models.Forum.Publish(forum)
+	if err != nil || !published {
+		return err
+	}
+	// Nobody was told about the mentions while the forum was hidden
+	author := &data.User{ID: forum.AuthorID}
+	if forum.Author != nil {
+		author.Name = forum.Author.Name
+	}
+	app.notifyMentionedInForum(forum, author)
+
+	app.background(func() {
+		subscribers, err := app.models.Subscriptions.GetSubscribers(forum.ID, 0)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{"forum_id": fmt.Sprint(forum.ID)})
+			return
+		}
+		ids := make([]int64, 0, len(subscribers))
+		for _, user := range subscribers {
+			ids = append(ids, user.ID)
+		}
+		app.notify(ids, data.NotificationPublished, actor, envelope{
+			"forum_id": forum.ID,
+			"title":    forum.Title,
+		})
+	})
+	return nil
+}
+
+// publishScheduledForums() publishes the scheduled forums whose time has
+// come. The schedule lives in the database, so the forums that fell due
+// while the server was down are published as soon as it starts again, and
+// Publish() makes sure each of them is announced once. It runs every minute
+// until the server shuts down
+func (app *application) publishScheduledForums() {
+	ticker := time.NewTicker(publishInterval)
+	defer ticker.Stop()
+	for {
+		ids, err := app.models.Forum.GetDue()
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+		for _, id := range ids {
+			forum, err := app.models.Forum.Get(id)
+			if err == nil {
+				err = app.publishForum(forum, nil)
+			}
+			if err != nil {
+				app.logger.PrintError(err, map[string]string{"forum_id": fmt.Sprint(id)})
+				continue
+			}
+			app.logger.PrintInfo("published scheduled forum", map[string]string{"forum_id": fmt.Sprint(id)})
+		}
+		select {
+		case <-ticker.C:
+		case <-app.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,215 @@
+// Filename: cmd/api/reactions.go
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"universityforum.miguelavila.net/internals/data"
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+// reactForumHandler for the "PUT /v1/forums/:id/reactions/:emoji" endpoint
+func (app *application) reactForumHandler(w http.ResponseWriter, r *http.Request) {
+	app.setReaction(w, r, "forum", true)
+}
+
+// unreactForumHandler for the "DELETE /v1/forums/:id/reactions/:emoji" endpoint
+func (app *application) unreactForumHandler(w http.ResponseWriter, r *http.Request) {
+	app.setReaction(w, r, "forum", false)
+}
+
+// listForumReactorsHandler for the "GET /v1/forums/:id/reactions/:emoji" endpoint
+func (app *application) listForumReactorsHandler(w http.ResponseWriter, r *http.Request) {
+	app.listReactors(w, r, "forum")
+}
+
+// reactReplyHandler for the "PUT /v1/replies/:id/reactions/:emoji" endpoint
+func (app *application) reactReplyHandler(w http.ResponseWriter, r *http.Request) {
+	app.setReaction(w, r, "reply", true)
+}
+
+// unreactReplyHandler for the "DELETE /v1/replies/:id/reactions/:emoji" endpoint
+func (app *application) unreactReplyHandler(w http.ResponseWriter, r *http.Request) {
+	app.setReaction(w, r, "reply", false)
+}
+
+// listReplyReactorsHandler for the "GET /v1/replies/:id/reactions/:emoji" endpoint
+func (app *application) listReplyReactorsHandler(w http.ResponseWriter, r *http.Request) {
+	app.listReactors(w, r, "reply")
+}
+
+// setReaction() adds or removes a reaction of the current user to a forum or
+// a reply and writes the updated reactions back to the client. Both
+// operations are idempotent
+func (app *application) setReaction(w http.ResponseWriter, r *http.Request, kind string, added bool) {
+	forumID, replyID, ok := app.readReactionTarget(w, r, kind)
+	if !ok {
+		return
+	}
+	emoji, ok := app.readEmojiParam(w, r)
+	if !ok {
+		return
+	}
+
+	user := app.contextGetUser(r)
+	var err error
+	if added {
+		err = app.models.Reactions.Insert(forumID, replyID, user.ID, emoji)
+	} else {
+		err = app.models.Reactions.Delete(forumID, replyID, user.ID, emoji)
+	}
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var reactions map[int64][]*data.Reaction
+	if kind == "forum" {
+		reactions, err = app.models.Reactions.GetForForums([]int64{forumID}, user.ID, app.config.reactions)
+	} else {
+		reactions, err = app.models.Reactions.GetForReplies([]int64{replyID}, user.ID, app.config.reactions)
+	}
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	result := reactions[forumID+replyID]
+	if result == nil {
+		result = []*data.Reaction{}
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"reactions": result}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// listReactors() writes a page of the users who reacted to a forum or a
+// reply with an emoji
+func (app *application) listReactors(w http.ResponseWriter, r *http.Request, kind string) {
+	forumID, replyID, ok := app.readReactionTarget(w, r, kind)
+	if !ok {
+		return
+	}
+	emoji, ok := app.readEmojiParam(w, r)
+	if !ok {
+		return
+	}
+	var filters data.Filters
+	v := validator.New()
+	qs := r.URL.Query()
+	filters.Page = app.readInt(qs, "page", 1, v)
+	filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	// The most recent reactions come first
+	filters.Sort = "-id"
+	filters.SortList = []string{"-id"}
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	reactors, metadata, err := app.models.Reactions.GetReactors(forumID, replyID, emoji, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"emoji": emoji, "users": reactors, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readReactionTarget() reads the ":id" of the forum or reply being reacted
+// to and makes sure the current user can see it. Exactly one of the ids
+// returned is set. The response has been sent when ok is false
+func (app *application) readReactionTarget(w http.ResponseWriter, r *http.Request, kind string) (forumID, replyID int64, ok bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return 0, 0, false
+	}
+	// Replies are only visible along with their forum
+	parentID := id
+	if kind == "reply" {
+		var reply *data.Reply
+		reply, err = app.models.Reply.GetVisible(id, app.contextGetUser(r).ID)
+		if err == nil {
+			parentID, replyID = reply.ForumID, reply.ID
+		}
+	} else {
+		forumID = id
+	}
+	if err == nil {
+		_, err = app.models.Forum.GetVisible(parentID, app.contextGetUser(r).ID)
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return 0, 0, false
+	}
+	return forumID, replyID, true
+}
+
+// readEmojiParam() reads the ":emoji" parameter, which has to be one of the
+// configured reactions. The response has been sent when ok is false
+func (app *application) readEmojiParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	emoji := httprouter.ParamsFromContext(r.Context()).ByName("emoji")
+	v := validator.New()
+	v.Check(validator.In(emoji, app.config.reactions...), "emoji", "must be one of "+strings.Join(app.config.reactions, " "))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return "", false
+	}
+	return emoji, true
+}
+
+// addForumReactions() fills in the reactions of a page of forums with a
+// single query
+func (app *application) addForumReactions(forums []*data.Forum, userID int64) error {
+	ids := make([]int64, 0, len(forums))
+	for _, forum := range forums {
+		ids = append(ids, forum.ID)
+	}
+	reactions, err := app.models.Reactions.GetForForums(ids, userID, app.config.reactions)
+	if err != nil {
+		return err
+	}
+	for _, forum := range forums {
+		forum.Reactions = reactions[forum.ID]
+	}
+	return nil
+}
+
+// addReplyReactions() fills in the reactions of a page of replies, and of
+// the replies nested under them, with a single query
+func (app *application) addReplyReactions(replies []*data.Reply, userID int64) error {
+	all := []*data.Reply{}
+	var walk func(replies []*data.Reply)
+	walk = func(replies []*data.Reply) {
+		for _, reply := range replies {
+			all = append(all, reply)
+			walk(reply.Replies)
+		}
+	}
+	walk(replies)
+
+	ids := make([]int64, 0, len(all))
+	for _, reply := range all {
+		ids = append(ids, reply.ID)
+	}
+	reactions, err := app.models.Reactions.GetForReplies(ids, userID, app.config.reactions)
+	if err != nil {
+		return err
+	}
+	for _, reply := range all {
+		reply.Reactions = reactions[reply.ID]
+	}
+	return nil
+}
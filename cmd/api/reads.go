@@ -0,0 +1,72 @@
+// Filename: cmd/api/reads.go
+
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"universityforum.miguelavila.net/internals/data"
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+// markForumReadHandler for the "POST /v1/forums/:id/read" endpoint. The
+// client sends the last reply it showed, or nothing to mark the whole forum
+// as read
+func (app *application) markForumReadHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	var input struct {
+		ReplyID int64 `json:"reply_id"`
+	}
+	if r.ContentLength != 0 {
+		err = app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+	// Make sure the forum exists
+	forum, err := app.models.Forum.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.ReplyID >= 0, "reply_id", "must not be negative")
+	if input.ReplyID > 0 {
+		reply, err := app.models.Reply.GetVisible(input.ReplyID, app.contextGetUser(r).ID)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("reply_id", "must reference an existing reply")
+		case err != nil:
+			app.serverErrorResponse(w, r, err)
+			return
+		case reply.ForumID != forum.ID:
+			v.AddError("reply_id", "must reference a reply of the same forum")
+		}
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	position, err := app.models.Reads.MarkRead(app.contextGetUser(r).ID, forum.ID, input.ReplyID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"read": position}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,316 @@
+// Filename: cmd/api/replies.go
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"universityforum.miguelavila.net/internals/data"
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+// createReplyHandler for the "POST /v1/forums/:id/replies" endpoint
+func (app *application) createReplyHandler(w http.ResponseWriter, r *http.Request) {
+	forumID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	// Make sure the forum being replied to exists
+	forum, err := app.models.Forum.GetVisible(forumID, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	// Locked forums take no new replies
+	if forum.Locked {
+		app.forumLockedResponse(w, r)
+		return
+	}
+	// Our target decode destination
+	var input struct {
+		Message  string `json:"message"`
+		ParentID *int64 `json:"parent_id"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// Copy the values from the input struct to a new Reply struct
+	reply := &data.Reply{
+		Message: input.Message,
+		UserID:  app.contextGetUser(r).ID,
+		ForumID: forumID,
+	}
+
+	// Initialize a new Validator instance
+	v := validator.New()
+
+	// A nested reply must answer another reply of the same forum
+	if input.ParentID != nil {
+		parent, err := app.models.Reply.GetVisible(*input.ParentID, app.contextGetUser(r).ID)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("parent_id", "must reference an existing reply")
+		case err != nil:
+			app.serverErrorResponse(w, r, err)
+			return
+		case parent.ForumID != forumID:
+			v.AddError("parent_id", "must reference a reply of the same forum")
+		default:
+			reply.ParentID = parent.ID
+		}
+	}
+
+	// Check the map to determine if there were any validation errors
+	if data.ValidateReply(v, reply); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Create a Reply
+	err = app.models.Reply.Insert(reply)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	// Let the users mentioned know
+	app.notifyMentionedInReply(reply, app.contextGetUser(r))
+	// and the users following the forum
+	app.notifySubscribers(forum, reply, app.contextGetUser(r))
+	app.publish(forumTopic(forum.ID), "reply.created", envelope{"reply": reply})
+	// Create a Location header for the newly created Reply
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/replies/%d", reply.ID))
+	err = app.writeJSON(w, http.StatusCreated, envelope{"reply": reply}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showReplyHandler for the "GET /v1/replies/:id" endpoint
+func (app *application) showReplyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	// Fetch the specific reply
+	reply, err := app.models.Reply.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.addReplyReactions([]*data.Reply{reply}, app.contextGetUser(r).ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"reply": reply}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateReplyHandler for the "PATCH /v1/replies/:id" endpoint
+func (app *application) updateReplyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	// Fetch the orginal record from the database
+	reply, err := app.models.Reply.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	// Only the author of a reply or a moderator may edit it
+	allowed, err := app.isOwnerOrModerator(app.contextGetUser(r), reply.UserID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		app.notPermittedResponse(w, r)
+		return
+	}
+	// A nil field means the client did not update it
+	var input struct {
+		Message *string `json:"message"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// Check for updates
+	if input.Message != nil {
+		reply.Message = *input.Message
+	}
+
+	v := validator.New()
+
+	if data.ValidateReply(v, reply); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	// Pass the updated Reply record to the Update() method
+	err = app.models.Reply.Update(reply, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	// Let the users newly mentioned know
+	app.notifyMentionedInReply(reply, app.contextGetUser(r))
+	app.publish(forumTopic(reply.ForumID), "reply.updated", envelope{"reply": reply})
+	err = app.writeJSON(w, http.StatusOK, envelope{"reply": reply}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteReplyHandler for the "DELETE /v1/replies/:id" endpoint
+func (app *application) deleteReplyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	reply, err := app.models.Reply.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	// Only the author of a reply or a moderator may delete it
+	allowed, err := app.isOwnerOrModerator(app.contextGetUser(r), reply.UserID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	if !allowed {
+		app.notPermittedResponse(w, r)
+		return
+	}
+	err = app.models.Reply.Delete(reply.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	app.publish(forumTopic(reply.ForumID), "reply.deleted", envelope{"reply_id": reply.ID})
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "reply successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The listRepliesHandler() allows the client to page through the replies
+// of a forum, either as a flat list or, with view=tree, as nested threads
+func (app *application) listRepliesHandler(w http.ResponseWriter, r *http.Request) {
+	forumID, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	_, err = app.models.Forum.GetVisible(forumID, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	// Create an input struct to hold our query parameters
+	var input struct {
+		View     string
+		ParentID int
+		Depth    int
+		Children int
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	// Get the tree information
+	input.View = app.readString(qs, "view", "flat")
+	input.ParentID = app.readInt(qs, "parent_id", 0, v)
+	input.Depth = app.readInt(qs, "depth", 3, v)
+	input.Children = app.readInt(qs, "children", 5, v)
+	// Get the page information
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	app.readCursorParams(qs, "replies", &input.Filters, v)
+	// Get the sort information
+	input.Filters.Sort = app.readString(qs, "sort", "id")
+	// Specific the allowed sort values
+	input.Filters.SortList = []string{"id", "created_at", "score", "-id", "-created_at", "-score"}
+	// Check for validation errors
+	v.Check(validator.In(input.View, "flat", "tree"), "view", "must be flat or tree")
+	v.Check(input.ParentID >= 0, "parent_id", "must not be negative")
+	v.Check(input.Depth > 0, "depth", "must be greater than zero")
+	v.Check(input.Depth <= 10, "depth", "must be a maximum of 10")
+	v.Check(input.Children > 0, "children", "must be greater than zero")
+	v.Check(input.Children <= 50, "children", "must be a maximum of 50")
+	if data.ValidateReplyFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var replies []*data.Reply
+	var metadata data.Metadata
+	if input.View == "tree" {
+		replies, metadata, err = app.models.Reply.GetTreeForForum(forumID, int64(input.ParentID), input.Depth, input.Children, input.Filters)
+	} else {
+		replies, metadata, err = app.models.Reply.GetAllForForum(forumID, input.Filters)
+	}
+	if err == nil {
+		err = app.addReplyReactions(replies, app.contextGetUser(r).ID)
+	}
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	app.setNextCursor("replies", &metadata)
+	err = app.writeJSON(w, http.StatusOK, envelope{"replies": replies, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,401 @@
+// Filename: cmd/api/revisions.go
+
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"universityforum.miguelavila.net/internals/data"
+	"universityforum.miguelavila.net/internals/diff"
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+// The listForumRevisionsHandler() returns the past versions of a forum
+func (app *application) listForumRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	_, err = app.models.Forum.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	filters, ok := app.readRevisionFilters(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, metadata, err := app.models.Revisions.GetAllForForum(id, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"revisions": revisions, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showForumRevisionHandler for the "GET /v1/forums/:id/revisions/:version" endpoint
+func (app *application) showForumRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	version, err := app.readVersionParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	forum, err := app.models.Forum.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	revision, err := app.forumRevision(forum, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"revision": revision}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The diffForumRevisionsHandler() compares two versions of a forum line by
+// line. "from" defaults to the version before "to", and "to" defaults to the
+// current version
+func (app *application) diffForumRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	forum, err := app.models.Forum.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+	to := app.readInt(qs, "to", int(forum.Version), v)
+	from := app.readInt(qs, "from", to-1, v)
+	v.Check(from > 0, "from", "must be greater than zero")
+	v.Check(to <= int(forum.Version), "to", "must not be greater than the current version")
+	v.Check(from < to, "from", "must be less than to")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Fetch both sides of the diff
+	var sides [2]*data.ForumRevision
+	for i, version := range []int{from, to} {
+		sides[i], err = app.forumRevision(forum, int32(version))
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+
+	result := envelope{
+		"from":        from,
+		"to":          to,
+		"title":       diff.Lines(sides[0].Title, sides[1].Title),
+		"description": diff.Lines(sides[0].Description, sides[1].Description),
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"diff": result}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The revertForumHandler() restores the title and description of a past
+// version. The client sends the version it expects the forum to be at, and
+// the revert is saved as a new version through the usual optimistic locking
+func (app *application) revertForumHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	version, err := app.readVersionParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	var input struct {
+		Version *int32 `json:"version"`
+	}
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	v.Check(input.Version != nil, "version", "must be provided")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	forum, err := app.models.Forum.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	// Someone edited the forum since the client last saw it
+	if forum.Version != *input.Version {
+		app.editConflictResponse(w, r)
+		return
+	}
+	revision, err := app.forumRevision(forum, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	forum.Title = revision.Title
+	forum.Description = revision.Description
+	err = app.models.Forum.Update(forum, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	// Let the users newly mentioned know
+	app.notifyMentionedInForum(forum, app.contextGetUser(r))
+	app.notifyModeration(forum, app.contextGetUser(r), "reverted")
+	app.publish(forumTopic(forum.ID), "forum.updated", envelope{"forum": forum})
+	err = app.writeJSON(w, http.StatusOK, envelope{"forum": forum}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The listReplyRevisionsHandler() returns the past versions of a reply
+func (app *application) listReplyRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	_, err = app.models.Reply.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	filters, ok := app.readRevisionFilters(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, metadata, err := app.models.Revisions.GetAllForReply(id, filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"revisions": revisions, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// showReplyRevisionHandler for the "GET /v1/replies/:id/revisions/:version" endpoint
+func (app *application) showReplyRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	version, err := app.readVersionParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	reply, err := app.models.Reply.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	revision, err := app.replyRevision(reply, version)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"revision": revision}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The diffReplyRevisionsHandler() compares two versions of a reply line by
+// line, with the same defaults as diffForumRevisionsHandler()
+func (app *application) diffReplyRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	reply, err := app.models.Reply.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+	qs := r.URL.Query()
+	to := app.readInt(qs, "to", int(reply.Version), v)
+	from := app.readInt(qs, "from", to-1, v)
+	v.Check(from > 0, "from", "must be greater than zero")
+	v.Check(to <= int(reply.Version), "to", "must not be greater than the current version")
+	v.Check(from < to, "from", "must be less than to")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Fetch both sides of the diff
+	var sides [2]*data.ReplyRevision
+	for i, version := range []int{from, to} {
+		sides[i], err = app.replyRevision(reply, int32(version))
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				app.notFoundResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+	}
+
+	result := envelope{
+		"from":    from,
+		"to":      to,
+		"message": diff.Lines(sides[0].Message, sides[1].Message),
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"diff": result}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// readRevisionFilters() reads the paging of a revision listing. A failed
+// validation response is sent when ok is false
+func (app *application) readRevisionFilters(w http.ResponseWriter, r *http.Request) (filters data.Filters, ok bool) {
+	v := validator.New()
+	qs := r.URL.Query()
+	filters.Page = app.readInt(qs, "page", 1, v)
+	filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	// The newest versions come first by default
+	filters.Sort = app.readString(qs, "sort", "-version")
+	filters.SortList = []string{"version", "-version"}
+	if data.ValidateFilters(v, filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return filters, false
+	}
+	return filters, true
+}
+
+// forumRevision() returns the content of a forum at a given version, which
+// may be the current one
+func (app *application) forumRevision(forum *data.Forum, version int32) (*data.ForumRevision, error) {
+	switch {
+	case version == forum.Version:
+		return &data.ForumRevision{
+			ForumID:     forum.ID,
+			Version:     forum.Version,
+			Title:       forum.Title,
+			Description: forum.Description,
+		}, nil
+	case version > forum.Version:
+		return nil, data.ErrRecordNotFound
+	default:
+		return app.models.Revisions.GetForForum(forum.ID, version)
+	}
+}
+
+// replyRevision() returns the message of a reply at a given version, which
+// may be the current one
+func (app *application) replyRevision(reply *data.Reply, version int32) (*data.ReplyRevision, error) {
+	switch {
+	case version == reply.Version:
+		return &data.ReplyRevision{
+			ReplyID: reply.ID,
+			Version: reply.Version,
+			Message: reply.Message,
+		}, nil
+	case version > reply.Version:
+		return nil, data.ErrRecordNotFound
+	default:
+		return app.models.Revisions.GetForReply(reply.ID, version)
+	}
+}
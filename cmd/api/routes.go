@@ -14,14 +14,84 @@ func (app *application) routes() http.Handler {
 	router.NotFound = http.HandlerFunc(app.notFoundResponse)
 	router.MethodNotAllowed = http.HandlerFunc(app.MethodNotAllowedReponse)
 	router.HandlerFunc(http.MethodGet, "/v1/healthcheck", app.healthcheckHandler)
+	// Named paths that share the "/v1/forums/:id" route
+	forumPaths := map[string]http.HandlerFunc{
+		"trash":   app.requiredPermission("forums:moderate", app.listTrashHandler),
+		"suggest": app.requiredPermission("forums:read", app.suggestForumsHandler),
+	}
 	router.HandlerFunc(http.MethodGet, "/v1/forums", app.requiredPermission("forums:read", app.listForumsHandler)) // remove permissions
 	router.HandlerFunc(http.MethodPost, "/v1/forums", app.requiredPermission("forums:write", app.createForumHandler))
-	router.HandlerFunc(http.MethodGet, "/v1/forums/:id", app.showForumHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/forums/:id", app.namedForumPaths(forumPaths, app.showForumHandler))
 	router.HandlerFunc(http.MethodPatch, "/v1/forums/:id", app.requiredPermission("forums:write", app.updateForumHandler))
 	router.HandlerFunc(http.MethodDelete, "/v1/forums/:id", app.requiredPermission("forums:write", app.deleteForumHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/forums/:id/restore", app.requiredPermission("forums:moderate", app.restoreForumHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/forums/:id/pin", app.requiredPermission("forums:moderate", app.pinForumHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/forums/:id/pin", app.requiredPermission("forums:moderate", app.unpinForumHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/forums/:id/lock", app.requiredPermission("forums:moderate", app.lockForumHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/forums/:id/lock", app.requiredPermission("forums:moderate", app.unlockForumHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/forums/:id/kind", app.requiredPermission("forums:moderate", app.updateForumKindHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/forums/:id/attachments", app.requiredPermission("forums:read", app.listForumAttachmentsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/forums/:id/attachments", app.requiredActivatedUser(app.createForumAttachmentHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/forums/:id/revisions", app.requiredPermission("forums:read", app.listForumRevisionsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/forums/:id/revisions/:version", app.requiredPermission("forums:read", app.showForumRevisionHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/forums/:id/revisions/:version/revert", app.requiredPermission("forums:moderate", app.revertForumHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/forums/:id/diff", app.requiredPermission("forums:read", app.diffForumRevisionsHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/forums/:id/like", app.requiredActivatedUser(app.likeForumHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/forums/:id/like", app.requiredActivatedUser(app.unlikeForumHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/forums/:id/reactions/:emoji", app.requiredPermission("forums:read", app.listForumReactorsHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/forums/:id/reactions/:emoji", app.requiredActivatedUser(app.reactForumHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/forums/:id/reactions/:emoji", app.requiredActivatedUser(app.unreactForumHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/forums/:id/poll/votes", app.requiredActivatedUser(app.votePollHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/forums/:id/read", app.requiredActivatedUser(app.markForumReadHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/forums/:id/bookmark", app.requiredActivatedUser(app.bookmarkForumHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/forums/:id/bookmark", app.requiredActivatedUser(app.unbookmarkForumHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/forums/:id/subscription", app.requiredActivatedUser(app.subscribeForumHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/forums/:id/subscription", app.requiredActivatedUser(app.unsubscribeForumHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/forums/:id/events", app.requiredPermission("forums:read", app.forumEventsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/forums/:id/replies", app.requiredPermission("forums:read", app.listRepliesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/forums/:id/replies", app.requiredActivatedUser(app.createReplyHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/replies/:id", app.showReplyHandler)
+	router.HandlerFunc(http.MethodPatch, "/v1/replies/:id", app.requiredActivatedUser(app.updateReplyHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/replies/:id", app.requiredActivatedUser(app.deleteReplyHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/replies/:id/revisions", app.requiredPermission("forums:read", app.listReplyRevisionsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/replies/:id/revisions/:version", app.requiredPermission("forums:read", app.showReplyRevisionHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/replies/:id/diff", app.requiredPermission("forums:read", app.diffReplyRevisionsHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/replies/:id/vote", app.requiredActivatedUser(app.voteReplyHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/replies/:id/vote", app.requiredActivatedUser(app.unvoteReplyHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/replies/:id/reactions/:emoji", app.requiredPermission("forums:read", app.listReplyReactorsHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/replies/:id/reactions/:emoji", app.requiredActivatedUser(app.reactReplyHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/replies/:id/reactions/:emoji", app.requiredActivatedUser(app.unreactReplyHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/replies/:id/accept", app.requiredActivatedUser(app.acceptReplyHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/replies/:id/accept", app.requiredActivatedUser(app.unacceptReplyHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/replies/:id/attachments", app.requiredPermission("forums:read", app.listReplyAttachmentsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/replies/:id/attachments", app.requiredActivatedUser(app.createReplyAttachmentHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/attachments/:id", app.requiredPermission("forums:read", app.showAttachmentHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/notifications", app.requiredActivatedUser(app.listNotificationsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/notifications/read", app.requiredActivatedUser(app.markNotificationsReadHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/notifications/unread-count", app.requiredActivatedUser(app.unreadNotificationsCountHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/notifications/events", app.requiredActivatedUser(app.notificationEventsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/search", app.requiredPermission("forums:read", app.searchHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/tags", app.requiredPermission("forums:read", app.listTagsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/categories", app.requiredPermission("forums:read", app.listCategoriesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/categories", app.requiredPermission("forums:moderate", app.createCategoryHandler))
 	router.HandlerFunc(http.MethodPost, "/v1/users", app.registerUserHandler)
 	router.HandlerFunc(http.MethodPut, "/v1/users/activate", app.activateUserHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/users/me/bookmarks", app.requiredActivatedUser(app.listBookmarksHandler))
 	router.HandlerFunc(http.MethodPost, "/v1/tokens/authentication", app.createAuthenticationTokenHandler)
 
 	return app.recoverPanic(app.enableCORS(app.rateLimit(app.authenticate(router))))
 }
+
+// httprouter does not allow a static path segment next to a wildcard, so paths
+// such as "/v1/forums/trash" are matched by the ":id" route. namedForumPaths()
+// sends those requests to their own handler and everything else to next
+func (app *application) namedForumPaths(paths map[string]http.HandlerFunc, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := httprouter.ParamsFromContext(r.Context()).ByName("id")
+		if handler, ok := paths[name]; ok {
+			handler(w, r)
+			return
+		}
+		next(w, r)
+	}
+}
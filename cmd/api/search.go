@@ -0,0 +1,83 @@
+// Filename: cmd/api/search.go
+
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"universityforum.miguelavila.net/internals/data"
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+// searchHandler for the "GET /v1/search" endpoint. It searches the forums,
+// the replies and the users at once; "type" is a comma separated list of the
+// kinds of results wanted, all of them by default
+func (app *application) searchHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Query string
+		Types []string
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	input.Query = strings.TrimSpace(app.readString(qs, "q", ""))
+	for _, t := range strings.Split(app.readString(qs, "type", strings.Join(data.SearchTypes, ",")), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			input.Types = append(input.Types, t)
+		}
+	}
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	// The best matches always come first
+	input.Filters.Sort = "relevance"
+	input.Filters.SortList = []string{"relevance"}
+	data.ValidateSearch(v, input.Query, input.Types)
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	results, metadata, err := app.models.Search.Search(input.Query, input.Types, app.contextGetUser(r).ID, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"results": results, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// The search documents of the forums whose replies changed are rebuilt this
+// often, going through this many queued changes at a time
+const (
+	searchRefreshInterval  = 30 * time.Second
+	searchRefreshBatchSize = 500
+)
+
+// refreshSearchVectors() keeps the replies part of the search documents of
+// the forums up to date. It runs until the server shuts down
+func (app *application) refreshSearchVectors() {
+	ticker := time.NewTicker(searchRefreshInterval)
+	defer ticker.Stop()
+	for {
+		// Keep going while whole batches are queued
+		for {
+			count, err := app.models.Forum.RefreshSearchVectors(searchRefreshBatchSize)
+			if err != nil {
+				app.logger.PrintError(err, nil)
+				break
+			}
+			if count < searchRefreshBatchSize {
+				break
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-app.done:
+			return
+		}
+	}
+}
@@ -25,6 +25,9 @@ func (app *application) serve() error {
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
 	}
+	// Event streams never finish on their own, so end them when the
+	// shutdown starts or Shutdown() would wait for them until it times out
+	srv.RegisterOnShutdown(app.events.Close)
 
 	// shutdown function should return its error to this channel
 	shutdownError := make(chan error)
@@ -56,6 +59,8 @@ func (app *application) serve() error {
 		app.logger.PrintInfo("completing background tasks", map[string]string{
 			"addr": srv.Addr,
 		})
+		// tell the background jobs to stop
+		close(app.done)
 		app.wg.Wait()
 		shutdownError <- nil
 	}()
@@ -0,0 +1,119 @@
+// Filename: cmd/api/subscriptions.go
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"universityforum.miguelavila.net/internals/data"
+	"universityforum.miguelavila.net/internals/mailer"
+)
+
+// subscribeForumHandler for the "PUT /v1/forums/:id/subscription" endpoint
+func (app *application) subscribeForumHandler(w http.ResponseWriter, r *http.Request) {
+	app.setForumSubscription(w, r, true)
+}
+
+// unsubscribeForumHandler for the "DELETE /v1/forums/:id/subscription" endpoint
+func (app *application) unsubscribeForumHandler(w http.ResponseWriter, r *http.Request) {
+	app.setForumSubscription(w, r, false)
+}
+
+// setForumSubscription() subscribes or unsubscribes the current user and
+// writes the forum back to the client. Both operations are idempotent
+func (app *application) setForumSubscription(w http.ResponseWriter, r *http.Request, subscribed bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	forum, err := app.models.Forum.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Subscriptions.Set(app.contextGetUser(r).ID, forum.ID, subscribed)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	forum.Subscribed = subscribed
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"forum": forum}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// notifySubscribers() tells the subscribers of a forum about a new reply in
+// their inbox and by mail, in the background. The mails go out in batches,
+// each over a single SMTP connection, so a busy forum does not open a
+// connection per subscriber. Users mentioned in the reply already got a mail
+// and are left out
+func (app *application) notifySubscribers(forum *data.Forum, reply *data.Reply, author *data.User) {
+	app.background(func() {
+		subscribers, err := app.models.Subscriptions.GetSubscribers(forum.ID, author.ID)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+			return
+		}
+		mentioned := make(map[int64]bool)
+		for _, user := range reply.Mentioned {
+			mentioned[user.ID] = true
+		}
+
+		// Fill the inboxes first, mail can be slow
+		ids := []int64{}
+		for _, user := range subscribers {
+			if !mentioned[user.ID] {
+				ids = append(ids, user.ID)
+			}
+		}
+		app.notify(ids, data.NotificationReply, author, envelope{
+			"forum_id": forum.ID,
+			"reply_id": reply.ID,
+			"excerpt":  excerpt(reply.Message),
+		})
+
+		batch := []mailer.Message{}
+		send := func() {
+			err := app.mailer.SendBatch("forum_reply.tmpl", batch)
+			if err != nil {
+				app.logger.PrintError(err, map[string]string{
+					"forum_id": fmt.Sprint(forum.ID),
+					"reply_id": fmt.Sprint(reply.ID),
+				})
+			}
+			batch = batch[:0]
+		}
+		for _, user := range subscribers {
+			if mentioned[user.ID] {
+				continue
+			}
+			batch = append(batch, mailer.Message{
+				Recipient: user.Email,
+				Data: map[string]interface{}{
+					"name":       user.Name,
+					"authorName": author.Name,
+					"forumTitle": forum.Title,
+					"path":       fmt.Sprintf("/v1/replies/%d", reply.ID),
+					"excerpt":    excerpt(reply.Message),
+				},
+			})
+			if len(batch) >= app.config.stmp.batchSize {
+				send()
+			}
+		}
+		if len(batch) > 0 {
+			send()
+		}
+	})
+}
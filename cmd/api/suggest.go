@@ -0,0 +1,108 @@
+// Filename: cmd/api/suggest.go
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"universityforum.miguelavila.net/internals/data"
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+// Title suggestions are requested on every keystroke, and the same prefixes
+// are typed over and over, so the answers are kept for a short while
+const (
+	suggestCacheTTL  = 30 * time.Second
+	suggestCacheSize = 1000
+)
+
+// suggestionCache holds recent title suggestions by query. The suggestions
+// only list published forums, so they are the same for every user
+type suggestionCache struct {
+	mu      sync.Mutex
+	entries map[string]suggestionEntry
+}
+
+type suggestionEntry struct {
+	suggestions []*data.Suggestion
+	expires     time.Time
+}
+
+func newSuggestionCache() *suggestionCache {
+	return &suggestionCache{entries: make(map[string]suggestionEntry)}
+}
+
+// get() returns the suggestions cached for key, if they have not expired
+func (c *suggestionCache) get(key string) ([]*data.Suggestion, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.suggestions, true
+}
+
+// put() caches the suggestions for key. When the cache is full the expired
+// entries are dropped, and all of them if that is not enough
+func (c *suggestionCache) put(key string, suggestions []*data.Suggestion) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if len(c.entries) >= suggestCacheSize {
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= suggestCacheSize {
+			c.entries = make(map[string]suggestionEntry)
+		}
+	}
+	c.entries[key] = suggestionEntry{suggestions: suggestions, expires: now.Add(suggestCacheTTL)}
+}
+
+// suggestForumsHandler for the "GET /v1/forums/suggest" endpoint. It offers
+// the titles of existing forums while a title or a search is being typed
+func (app *application) suggestForumsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Query string
+		Limit int
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	input.Query = strings.TrimSpace(app.readString(qs, "q", ""))
+	input.Limit = app.readInt(qs, "limit", 10, v)
+	v.Check(input.Query != "", "q", "must be provided")
+	v.Check(len(input.Query) <= 200, "q", "must not be more than 200 bytes long")
+	v.Check(input.Limit > 0, "limit", "must be greater than zero")
+	v.Check(input.Limit <= 20, "limit", "must be a maximum of 20")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	key := fmt.Sprintf("%d:%s", input.Limit, strings.ToLower(input.Query))
+	suggestions, ok := app.suggestions.get(key)
+	if !ok {
+		var err error
+		suggestions, err = app.models.Forum.Suggest(input.Query, input.Limit)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		app.suggestions.put(key, suggestions)
+	}
+
+	// Let the browser reuse the answer too
+	headers := make(http.Header)
+	headers.Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(suggestCacheTTL.Seconds())))
+	err := app.writeJSON(w, http.StatusOK, envelope{"suggestions": suggestions}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,40 @@
+// Filename: cmd/api/tags.go
+
+package main
+
+import (
+	"net/http"
+
+	"universityforum.miguelavila.net/internals/data"
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+// The listTagsHandler() returns the tags in use with their usage counts so
+// the client can build a tag cloud
+func (app *application) listTagsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	// Get the page information
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 50, v)
+	// The most used tags come first by default
+	input.Filters.Sort = app.readString(qs, "sort", "-usage_count")
+	input.Filters.SortList = []string{"name", "usage_count", "-name", "-usage_count"}
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	tags, metadata, err := app.models.Tags.GetAll(input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"tags": tags, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
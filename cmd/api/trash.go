@@ -0,0 +1,95 @@
+// Filename: cmd/api/trash.go
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"universityforum.miguelavila.net/internals/data"
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+// The listTrashHandler() allows moderators to see the deleted forums
+func (app *application) listTrashHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.Filters
+	}
+	v := validator.New()
+	qs := r.URL.Query()
+	// Get the page information
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	// The most recently deleted forums come first by default
+	input.Filters.Sort = app.readString(qs, "sort", "-deleted_at")
+	input.Filters.SortList = []string{"id", "title", "deleted_at", "-id", "-title", "-deleted_at"}
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	forums, metadata, err := app.models.Forum.GetTrash(input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"forums": forums, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// restoreForumHandler for the "POST /v1/forums/:id/restore" endpoint
+func (app *application) restoreForumHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	// Send a 404 Not Found if the forum is not in the trash
+	err = app.models.Forum.Restore(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	forum, err := app.models.Forum.Get(id)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	app.notifyModeration(forum, app.contextGetUser(r), "restored")
+	err = app.writeJSON(w, http.StatusOK, envelope{"forum": forum}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// purgeTrash() permanently removes the forums that have been in the trash
+// longer than the configured retention period. It runs every hour until the
+// server shuts down
+func (app *application) purgeTrash() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		purged, err := app.models.Forum.Purge(app.config.trash.retention)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		} else if purged > 0 {
+			app.logger.PrintInfo("purged deleted forums", map[string]string{
+				"count": fmt.Sprintf("%d", purged),
+			})
+		}
+		select {
+		case <-ticker.C:
+		case <-app.done:
+			return
+		}
+	}
+}
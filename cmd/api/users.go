@@ -15,6 +15,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 	// hold data from the request body
 	var input struct {
 		Name     string `json:"name"`
+		Username string `json:"username"`
 		Email    string `json:"email"`
 		Password string `json:"password"`
 	}
@@ -30,6 +31,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 	// Copy data to a new struct
 	user := &data.User{
 		Name:      input.Name,
+		Username:  input.Username,
 		Email:     input.Email,
 		Activated: false,
 	}
@@ -57,6 +59,9 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		case errors.Is(err, data.ErrDuplicateEmail):
 			v.AddError("email", "user with this email already exists")
 			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrDuplicateUsername):
+			v.AddError("username", "user with this username already exists")
+			app.failedValidationResponse(w, r, v.Errors)
 		default:
 			app.serverErrorResponse(w, r, err)
 		}
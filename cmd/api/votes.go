@@ -0,0 +1,78 @@
+// Filename: cmd/api/votes.go
+
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"universityforum.miguelavila.net/internals/data"
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+// voteReplyHandler for the "PUT /v1/replies/:id/vote" endpoint
+func (app *application) voteReplyHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Value *int `json:"value"`
+	}
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	v := validator.New()
+	v.Check(input.Value != nil, "value", "must be provided")
+	v.Check(input.Value == nil || *input.Value == 1 || *input.Value == -1, "value", "must be 1 or -1")
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	app.setReplyVote(w, r, *input.Value)
+}
+
+// unvoteReplyHandler for the "DELETE /v1/replies/:id/vote" endpoint
+func (app *application) unvoteReplyHandler(w http.ResponseWriter, r *http.Request) {
+	app.setReplyVote(w, r, 0)
+}
+
+// setReplyVote() records or withdraws the vote of the current user and writes
+// the reply with its new score back to the client. Both operations are
+// idempotent
+func (app *application) setReplyVote(w http.ResponseWriter, r *http.Request, value int) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	reply, err := app.models.Reply.GetVisible(id, app.contextGetUser(r).ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	// Users cannot raise their own reputation
+	user := app.contextGetUser(r)
+	if reply.UserID == user.ID {
+		app.failedValidationResponse(w, r, map[string]string{"reply": "must not be your own reply"})
+		return
+	}
+
+	reply.Score, err = app.models.Votes.Set(reply.ID, user.ID, value)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	err = app.writeJSON(w, http.StatusOK, envelope{"reply": reply, "my_vote": value}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,79 @@
+// Filename: cmd/maintenance/main.go
+
+// The maintenance command runs one-off jobs against the forum database.
+//
+//	go run ./cmd/maintenance -db-dsn=... recompute-reputation
+//	go run ./cmd/maintenance -db-dsn=... recompute-scores
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	_ "github.com/lib/pq"
+	"universityforum.miguelavila.net/internals/data"
+	"universityforum.miguelavila.net/internals/jsonlog"
+)
+
+// The jobs that can be run, by name
+var jobs = map[string]func(models *data.Models) error{
+	// Rebuild the reputation of every user from the votes and accepted
+	// answers they received
+	"recompute-reputation": func(models *data.Models) error {
+		return models.User.RecomputeReputation()
+	},
+	// Rebuild the score of every reply from its votes
+	"recompute-scores": func(models *data.Models) error {
+		return models.Votes.RecomputeScores()
+	},
+}
+
+func main() {
+	var dsn string
+	flag.StringVar(&dsn, "db-dsn", os.Getenv("FORUM_DB_DSN"), "PostgreSQL DSN")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] job\n\nJobs:\n", os.Args[0])
+		for name := range jobs {
+			fmt.Fprintf(flag.CommandLine.Output(), "  %s\n", name)
+		}
+		fmt.Fprintln(flag.CommandLine.Output(), "\nFlags:")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	logger := jsonlog.New(os.Stdout, jsonlog.LevelInfo)
+
+	job, ok := jobs[flag.Arg(0)]
+	if flag.NArg() != 1 || !ok {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+	defer db.Close()
+
+	// Make sure the database can be reached before starting
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = db.PingContext(ctx)
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
+	start := time.Now()
+	err = job(data.NewModels(db))
+	if err != nil {
+		logger.PrintFatal(err, map[string]string{"job": flag.Arg(0)})
+	}
+	logger.PrintInfo("job finished", map[string]string{
+		"job":      flag.Arg(0),
+		"duration": time.Since(start).String(),
+	})
+}
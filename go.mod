@@ -1,6 +1,6 @@
 module universityforum.miguelavila.net
 
-go 1.19
+go 1.20
 
 require github.com/julienschmidt/httprouter v1.3.0
 
@@ -9,8 +9,15 @@ require github.com/lib/pq v1.10.2
 require golang.org/x/time v0.1.0
 
 require (
-	golang.org/x/crypto v0.1.0
+	github.com/microcosm-cc/bluemonday v1.0.27
+	github.com/yuin/goldmark v1.7.8
+	golang.org/x/crypto v0.24.0
 	gopkg.in/mail.v2 v2.3.1
 )
 
-require gopkg.in/alexcesaro/quotedprintable.v3 v3.0.0-20150716171945-2caba252f4dc // indirect
+require (
+	github.com/aymerick/douceur v0.2.0 // indirect
+	github.com/gorilla/css v1.0.1 // indirect
+	golang.org/x/net v0.26.0 // indirect
+	gopkg.in/alexcesaro/quotedprintable.v3 v3.0.0-20150716171945-2caba252f4dc // indirect
+)
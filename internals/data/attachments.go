@@ -0,0 +1,253 @@
+// Filename : internal/data/attachments.go
+
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// An Attachment is a file uploaded to a forum or to a reply. Exactly one of
+// ForumID and ReplyID is set
+type Attachment struct {
+	ID          int64     `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	ForumID     int64     `json:"forum_id,omitempty"`
+	ReplyID     int64     `json:"reply_id,omitempty"`
+	UploaderID  int64     `json:"uploader_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      []byte    `json:"-"`
+}
+
+// StorageKey() returns the key the content of the attachment is stored
+// under. Attachments with the same content share it
+func (a *Attachment) StorageKey() string {
+	return hex.EncodeToString(a.SHA256)
+}
+
+// define an AttachmentModel object that wraps a sql.DB connection pool
+type AttachmentModel struct {
+	DB *sql.DB
+}
+
+// lockContent takes a transaction level lock on the content $1 of
+// attachments. It keeps RemoveDeleted() from removing content that an
+// attachment being inserted is about to use
+const lockContent = `SELECT pg_advisory_xact_lock(('x' || left(encode($1::bytea, 'hex'), 16))::bit(64)::bigint)`
+
+// Insert() records a new Attachment. The content has to be stored after the
+// attachment is recorded, so that RemoveDeleted() cannot remove it in between
+func (m AttachmentModel) Insert(attachment *Attachment) error {
+	query := `
+		INSERT INTO attachments (forum_id, reply_id, uploader_id, filename, content_type, size, sha256)
+		VALUES (NULLIF($1, 0), NULLIF($2, 0), $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+	args := []interface{}{
+		attachment.ForumID,
+		attachment.ReplyID,
+		attachment.UploaderID,
+		attachment.Filename,
+		attachment.ContentType,
+		attachment.Size,
+		attachment.SHA256,
+	}
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, lockContent, attachment.SHA256)
+	if err != nil {
+		return err
+	}
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&attachment.ID, &attachment.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete() removes an attachment. Its content is queued for removal like
+// the content of any other deleted attachment
+func (m AttachmentModel) Delete(id int64) error {
+	query := `
+		DELETE FROM attachments
+		WHERE id = $1
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, id)
+	return err
+}
+
+// RemoveDeleted() goes through up to batchSize of the queued contents of
+// deleted attachments and calls remove with the storage key of those that
+// no attachment uses anymore. It returns how many queued contents it went
+// through. The contents stay queued if remove fails
+func (m AttachmentModel) RemoveDeleted(batchSize int, remove func(key string) error) (int, error) {
+	// Removing files may take a while
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		DELETE FROM attachment_deletions
+		WHERE id IN (
+			SELECT id FROM attachment_deletions
+			ORDER BY id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING sha256
+	`
+	rows, err := tx.QueryContext(ctx, query, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	count := 0
+	contents := make(map[string][]byte)
+	for rows.Next() {
+		var content []byte
+		err := rows.Scan(&content)
+		if err != nil {
+			return 0, err
+		}
+		count++
+		contents[hex.EncodeToString(content)] = content
+	}
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	query = `
+		SELECT EXISTS (SELECT 1 FROM attachments WHERE sha256 = $1)
+	`
+	for key, content := range contents {
+		// The lock is held until the content is gone, so an attachment
+		// inserted meanwhile stores it again
+		_, err = tx.ExecContext(ctx, lockContent, content)
+		if err != nil {
+			return 0, err
+		}
+		var used bool
+		err = tx.QueryRowContext(ctx, query, content).Scan(&used)
+		if err != nil {
+			return 0, err
+		}
+		if used {
+			continue
+		}
+		err = remove(key)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return count, tx.Commit()
+}
+
+// Get() allows us to retrieve a specific Attachment
+func (m AttachmentModel) Get(id int64) (*Attachment, error) {
+	// Ensure that there is a valid id
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+	query := `
+		SELECT id, created_at, COALESCE(forum_id, 0), COALESCE(reply_id, 0),
+		       COALESCE(uploader_id, 0), filename, content_type, size, sha256
+		FROM attachments
+		WHERE id = $1
+	`
+	var attachment Attachment
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&attachment.ID,
+		&attachment.CreatedAt,
+		&attachment.ForumID,
+		&attachment.ReplyID,
+		&attachment.UploaderID,
+		&attachment.Filename,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.SHA256,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &attachment, nil
+}
+
+// The GetAllFor() method returns the attachments of a forum, or of a reply
+// when forumID is 0, oldest first
+func (m AttachmentModel) GetAllFor(forumID, replyID int64) ([]*Attachment, error) {
+	query := `
+		SELECT id, created_at, COALESCE(forum_id, 0), COALESCE(reply_id, 0),
+		       COALESCE(uploader_id, 0), filename, content_type, size, sha256
+		FROM attachments
+		WHERE ($1 <> 0 AND forum_id = $1) OR ($1 = 0 AND reply_id = $2)
+		ORDER BY id ASC
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, forumID, replyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []*Attachment{}
+	for rows.Next() {
+		var attachment Attachment
+		err := rows.Scan(
+			&attachment.ID,
+			&attachment.CreatedAt,
+			&attachment.ForumID,
+			&attachment.ReplyID,
+			&attachment.UploaderID,
+			&attachment.Filename,
+			&attachment.ContentType,
+			&attachment.Size,
+			&attachment.SHA256,
+		)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, &attachment)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
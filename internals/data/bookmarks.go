@@ -0,0 +1,136 @@
+// Filename : internal/data/bookmarks.go
+
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+// A Bookmark is a forum saved by a user. Folder and Note are personal to the
+// user; an empty folder means the bookmark is not filed anywhere
+type Bookmark struct {
+	Folder    string    `json:"folder"`
+	Note      string    `json:"note"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// define a BookmarkModel object that wraps a sql.DB connection pool
+type BookmarkModel struct {
+	DB *sql.DB
+}
+
+func ValidateBookmark(v *validator.Validator, bookmark *Bookmark) {
+	v.Check(len(bookmark.Folder) <= 50, "folder", "must not be more than 50 bytes long")
+	v.Check(len(bookmark.Note) <= 1000, "note", "must not be more than 1000 bytes long")
+}
+
+// Set() saves a forum for a user, or replaces the folder and note of an
+// existing bookmark. The time it was first saved is kept
+func (m BookmarkModel) Set(userID, forumID int64, bookmark *Bookmark) error {
+	query := `
+		INSERT INTO bookmarks (user_id, forum_id, folder, note)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, forum_id) DO UPDATE
+		SET folder = EXCLUDED.folder, note = EXCLUDED.note
+		RETURNING created_at
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	args := []interface{}{userID, forumID, bookmark.Folder, bookmark.Note}
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&bookmark.CreatedAt)
+}
+
+// Delete() removes the bookmark of a user from a forum. Removing a bookmark
+// that does not exist is a no-op
+func (m BookmarkModel) Delete(userID, forumID int64) error {
+	query := `
+		DELETE FROM bookmarks
+		WHERE user_id = $1 AND forum_id = $2
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID, forumID)
+	return err
+}
+
+// Exists() reports whether a user bookmarked a forum
+func (m BookmarkModel) Exists(userID, forumID int64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM bookmarks
+			WHERE user_id = $1 AND forum_id = $2
+		)
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	var exists bool
+	err := m.DB.QueryRowContext(ctx, query, userID, forumID).Scan(&exists)
+	return exists, err
+}
+
+// The GetAll() method returns the forums bookmarked by a user, each with its
+// bookmark. Only the bookmarks in folder are listed unless it is empty.
+// Forums in the trash are left out until they are restored, and so are the
+// forums of others that are no longer visible
+func (m BookmarkModel) GetAll(userID int64, folder string, filters Filters) ([]*Forum, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), %s,
+		       EXISTS (SELECT 1 FROM forumslikes WHERE forums_id = forums.id AND users_id = $1),
+		       bookmarks.folder, bookmarks.note, bookmarks.created_at AS bookmarked
+		FROM bookmarks
+		INNER JOIN forums ON forums.id = bookmarks.forum_id
+		WHERE bookmarks.user_id = $1
+		AND forums.deleted_at IS NULL
+		AND (forums.status = 'published' OR forums.author_id = $1)
+		AND (bookmarks.folder = $2 OR $2 = '')
+		ORDER BY %s %s, id ASC
+		LIMIT $3 OFFSET $4`, forumColumns, filters.sortColumn(), filters.sortOrder())
+
+	// Create a 3-second-timout context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, folder, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	forums := []*Forum{}
+	for rows.Next() {
+		var row forumRow
+		var likedByMe bool
+		var bookmark Bookmark
+		dest := append([]interface{}{&totalRecords}, row.dest()...)
+		dest = append(dest, &likedByMe, &bookmark.Folder, &bookmark.Note, &bookmark.CreatedAt)
+		err := rows.Scan(dest...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		forum := row.result()
+		forum.LikedByMe = likedByMe
+		forum.Bookmarked = true
+		forum.Bookmark = &bookmark
+		forums = append(forums, forum)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return forums, metadata, nil
+}
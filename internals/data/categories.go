@@ -0,0 +1,140 @@
+// Filename : internal/data/categories.go
+
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+var (
+	ErrDuplicateCategory = errors.New("duplicate category")
+)
+
+// A Category groups forums. Categories nest, and Path holds the names of the
+// category and its ancestors, e.g. "Faculty of ICT > CMPS"
+type Category struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"-"`
+	Name      string    `json:"name"`
+	ParentID  int64     `json:"parent_id,omitempty"`
+	Path      string    `json:"path"`
+}
+
+// define a CategoryModel object that wraps a sql.DB connection pool
+type CategoryModel struct {
+	DB *sql.DB
+}
+
+func ValidateCategory(v *validator.Validator, category *Category) {
+	v.Check(category.Name != "", "name", "must be provided")
+	v.Check(len(category.Name) <= 100, "name", "must not be more than 100 bytes long")
+	// The separator is reserved for the path
+	v.Check(!strings.Contains(category.Name, ">"), "name", "must not contain '>'")
+}
+
+// Insert() allows us to create a new Category under an optional parent
+func (m CategoryModel) Insert(category *Category) error {
+	query := `
+		INSERT INTO categories (name, parent_id, path)
+		SELECT $1, NULLIF($2, 0),
+		       COALESCE((SELECT path || ' > ' FROM categories WHERE id = $2), '') || $1
+		RETURNING id, created_at, path
+	`
+	args := []interface{}{category.Name, category.ParentID}
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&category.ID, &category.CreatedAt, &category.Path)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "categories_path_key"`:
+			return ErrDuplicateCategory
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// Get() allows us to retrieve a specific Category
+func (m CategoryModel) Get(id int64) (*Category, error) {
+	// Ensure that there is a valid id
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+	query := `
+		SELECT id, created_at, name, COALESCE(parent_id, 0), path
+		FROM categories
+		WHERE id = $1
+	`
+	var category Category
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&category.ID,
+		&category.CreatedAt,
+		&category.Name,
+		&category.ParentID,
+		&category.Path,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	return &category, nil
+}
+
+// The GetAll() method returns every category ordered by path, so that each
+// category is listed right after its parent
+func (m CategoryModel) GetAll() ([]*Category, error) {
+	query := `
+		SELECT id, created_at, name, COALESCE(parent_id, 0), path
+		FROM categories
+		ORDER BY path ASC
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []*Category{}
+	for rows.Next() {
+		var category Category
+		err := rows.Scan(
+			&category.ID,
+			&category.CreatedAt,
+			&category.Name,
+			&category.ParentID,
+			&category.Path,
+		)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, &category)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"fmt"
 	"math"
 	"strings"
 
@@ -12,6 +13,19 @@ type Filters struct {
 	PageSize int
 	Sort     string
 	SortList []string
+	// Cursor continues a listing after the last row of a previous page
+	// instead of skipping Page - 1 pages. Only some listings support it
+	Cursor *Cursor
+	// NoTotal leaves out counting the records, which takes going through
+	// all of them
+	NoTotal bool
+}
+
+// A Cursor is the position of a row in a listing: the sort it was listed by
+// and the values of the sort keys of the row, as text
+type Cursor struct {
+	Sort string   `json:"sort"`
+	Keys []string `json:"keys"`
 }
 
 func ValidateFilters(v *validator.Validator, f Filters) {
@@ -22,6 +36,18 @@ func ValidateFilters(v *validator.Validator, f Filters) {
 	v.Check(f.PageSize <= 100, "page_size", "must be a maximum of 100")
 	// Check that the sort parameter matches a value in the acceptable sort list
 	v.Check(validator.In(f.Sort, f.SortList...), "sort", "invalid sort value")
+	// A cursor only makes sense in the order it was made in
+	if f.Cursor != nil {
+		v.Check(f.Cursor.Sort == f.Sort, "cursor", "does not match the sort value")
+	}
+}
+
+// validateCursor() checks that the cursor of f holds a value for each key of
+// the order k of its listing. The sort must already be valid
+func validateCursor(v *validator.Validator, f Filters, k keyset) {
+	if f.Cursor != nil {
+		v.Check(len(f.Cursor.Keys) == len(k), "cursor", "must be a cursor returned by this listing")
+	}
 }
 
 // The sortColumn() method safety extracts the sort field query parameter
@@ -47,11 +73,95 @@ func (f Filters) limit() int {
 	return f.PageSize
 }
 
-// The offset() method calculates the OFFSET
+// The offset() method calculates the OFFSET. Nothing is skipped when
+// continuing from a cursor
 func (f Filters) offset() int {
+	if f.Cursor != nil {
+		return 0
+	}
 	return (f.Page - 1) * f.PageSize
 }
 
+// The total() method is the select expression counting the records of a
+// listing. The count is left out when it is not wanted, and after a cursor,
+// where it would only count the records left
+func (f Filters) total() string {
+	if f.NoTotal || f.Cursor != nil {
+		return "0"
+	}
+	return "COUNT(*) OVER()"
+}
+
+// A sortKey is an expression a listing is ordered by
+type sortKey struct {
+	expr string
+	desc bool
+}
+
+// A keyset is the full order of a listing, ending with a unique key so
+// that every row has its own position
+type keyset []sortKey
+
+// The orderBy() method is the ORDER BY clause of the listing
+func (k keyset) orderBy() string {
+	order := make([]string, len(k))
+	for i, key := range k {
+		order[i] = key.expr + " ASC"
+		if key.desc {
+			order[i] = key.expr + " DESC"
+		}
+	}
+	return strings.Join(order, ", ")
+}
+
+// The values() method is a select expression with the values of the keys of
+// a row as text, which is what a Cursor keeps. Postgres turns the text back
+// into the type of each key without losing any precision
+func (k keyset) values() string {
+	values := make([]string, len(k))
+	for i, key := range k {
+		values[i] = fmt.Sprintf("(%s)::text", key.expr)
+	}
+	return "ARRAY[" + strings.Join(values, ", ") + "]"
+}
+
+// The after() method is a condition keeping the rows that come after the
+// cursor, along with the arguments it refers to, numbered from param. Every
+// row is kept when there is no cursor. The cursor is checked beforehand by
+// validateCursor()
+func (k keyset) after(cursor *Cursor, param int) (string, []interface{}) {
+	if cursor == nil {
+		return "TRUE", nil
+	}
+	if len(cursor.Keys) != len(k) {
+		panic("cursor does not match the sort: " + cursor.Sort)
+	}
+	// A row comes after the cursor when it has the same values for the
+	// first keys and a later value for the next one
+	args := make([]interface{}, len(k))
+	alternatives := make([]string, len(k))
+	for i, key := range k {
+		args[i] = cursor.Keys[i]
+		conditions := []string{}
+		for j := 0; j < i; j++ {
+			conditions = append(conditions, fmt.Sprintf("%s = $%d", k[j].expr, param+j))
+		}
+		operator := ">"
+		if key.desc {
+			operator = "<"
+		}
+		conditions = append(conditions, fmt.Sprintf("%s %s $%d", key.expr, operator, param+i))
+		alternatives[i] = "(" + strings.Join(conditions, " AND ") + ")"
+	}
+	// The bound on the first key alone lets an index skip the earlier rows
+	operator := ">="
+	if k[0].desc {
+		operator = "<="
+	}
+	condition := fmt.Sprintf("(%s %s $%d AND (%s))", k[0].expr, operator, param, strings.Join(alternatives, " OR "))
+	return condition, args
+}
+
 // The Metadata type contains metadata to help with pagination
 type Metadata struct {
 	CurrentPage  int `json:"current_page,omitempty"`
@@ -59,6 +169,14 @@ type Metadata struct {
 	FirstPage    int `json:"first_page,omitempty"`
 	LastPage     int `json:"last_page,omitempty"`
 	TotalRecords int `json:"total_records,omitempty"`
+	// NextCursor continues the listing after this page. There is none on
+	// the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Next is the position NextCursor is made from
+	Next *Cursor `json:"-"`
+	// Facets counts the matches of each kind of a search, including the
+	// kinds that were filtered out
+	Facets map[string]int `json:"facets,omitempty"`
 }
 
 // The calculateMetadata() function computes the values for the Metadata fields
@@ -74,3 +192,24 @@ func calculateMetadata(totalRecrods int, page int, pageSize int) Metadata {
 		TotalRecords: totalRecrods,
 	}
 }
+
+// The calculateKeysetMetadata() function computes the Metadata of a page of
+// a listing supporting cursors. more tells whether rows are left after the
+// page, and last holds the sort key values of the last row of the page
+func calculateKeysetMetadata(totalRecords int, more bool, last []string, filters Filters) Metadata {
+	var metadata Metadata
+	switch {
+	case filters.Cursor != nil:
+		metadata.PageSize = filters.PageSize
+	case filters.NoTotal:
+		metadata.CurrentPage = filters.Page
+		metadata.PageSize = filters.PageSize
+		metadata.FirstPage = 1
+	default:
+		metadata = calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	}
+	if more {
+		metadata.Next = &Cursor{Sort: filters.Sort, Keys: last}
+	}
+	return metadata
+}
@@ -0,0 +1,90 @@
+// Filename: internal/data/filters_test.go
+
+package data
+
+import (
+	"reflect"
+	"testing"
+
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+func TestKeysetAfter(t *testing.T) {
+	keys := keyset{{expr: "forums.pinned", desc: true}, {expr: "forums.title"}, {expr: "forums.id"}}
+
+	condition, args := keys.after(nil, 3)
+	if condition != "TRUE" || args != nil {
+		t.Errorf("got %q %v without a cursor; want TRUE and no arguments", condition, args)
+	}
+
+	cursor := &Cursor{Sort: "title", Keys: []string{"true", "Go", "7"}}
+	condition, args = keys.after(cursor, 3)
+	want := "(forums.pinned <= $3 AND ((forums.pinned < $3) OR " +
+		"(forums.pinned = $3 AND forums.title > $4) OR " +
+		"(forums.pinned = $3 AND forums.title = $4 AND forums.id > $5)))"
+	if condition != want {
+		t.Errorf("got condition %q; want %q", condition, want)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"true", "Go", "7"}) {
+		t.Errorf("got arguments %v; want the keys of the cursor", args)
+	}
+}
+
+func TestKeysetOrderBy(t *testing.T) {
+	keys := keyset{{expr: "forums.pinned", desc: true}, {expr: "forums.id"}}
+	if got, want := keys.orderBy(), "forums.pinned DESC, forums.id ASC"; got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+	if got, want := keys.values(), "ARRAY[(forums.pinned)::text, (forums.id)::text]"; got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestValidateCursor(t *testing.T) {
+	forumSorts := []string{"id", "title", "likes", "relevance", "-id", "-title", "-likes"}
+	replySorts := []string{"id", "created_at", "score", "-id", "-created_at", "-score"}
+	tests := []struct {
+		name     string
+		validate func(*validator.Validator, Filters)
+		sorts    []string
+		sort     string
+		cursor   *Cursor
+		valid    bool
+	}{
+		{"forums without a cursor", ValidateForumFilters, forumSorts, "title", nil, true},
+		{"forums by id", ValidateForumFilters, forumSorts, "id", &Cursor{Sort: "id", Keys: []string{"false", "1"}}, true},
+		{"forums by title", ValidateForumFilters, forumSorts, "-title", &Cursor{Sort: "-title", Keys: []string{"false", "Go", "1"}}, true},
+		{"forums by relevance", ValidateForumFilters, forumSorts, "relevance", &Cursor{Sort: "relevance", Keys: []string{"true", "0.5", "1"}}, true},
+		{"forums by relevance missing pinned", ValidateForumFilters, forumSorts, "relevance", &Cursor{Sort: "relevance", Keys: []string{"0.5", "1"}}, false},
+		{"forums missing keys", ValidateForumFilters, forumSorts, "title", &Cursor{Sort: "title", Keys: []string{"false"}}, false},
+		{"forums without keys", ValidateForumFilters, forumSorts, "title", &Cursor{Sort: "title"}, false},
+		{"forums extra keys", ValidateForumFilters, forumSorts, "id", &Cursor{Sort: "id", Keys: []string{"false", "1", "2"}}, false},
+		{"forums other sort", ValidateForumFilters, forumSorts, "title", &Cursor{Sort: "id", Keys: []string{"false", "1"}}, false},
+		{"forums unknown sort", ValidateForumFilters, forumSorts, "bogus", &Cursor{Sort: "bogus"}, false},
+		{"replies by id", ValidateReplyFilters, replySorts, "id", &Cursor{Sort: "id", Keys: []string{"false", "1"}}, true},
+		{"replies by score", ValidateReplyFilters, replySorts, "-score", &Cursor{Sort: "-score", Keys: []string{"false", "3", "1"}}, true},
+		{"replies missing keys", ValidateReplyFilters, replySorts, "-score", &Cursor{Sort: "-score", Keys: []string{"false", "3"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			filters := Filters{Page: 1, PageSize: 20, Sort: tt.sort, SortList: tt.sorts, Cursor: tt.cursor}
+			tt.validate(v, filters)
+			if v.Valid() != tt.valid {
+				t.Errorf("got valid %t; want %t (errors %v)", v.Valid(), tt.valid, v.Errors)
+			}
+		})
+	}
+}
+
+func TestCalculateKeysetMetadata(t *testing.T) {
+	filters := Filters{Page: 1, PageSize: 2, Sort: "id"}
+	metadata := calculateKeysetMetadata(5, true, []string{"false", "2"}, filters)
+	if metadata.Next == nil || metadata.Next.Sort != "id" || !reflect.DeepEqual(metadata.Next.Keys, []string{"false", "2"}) {
+		t.Errorf("got next %+v; want the last row of the page", metadata.Next)
+	}
+	metadata = calculateKeysetMetadata(5, false, []string{"false", "5"}, filters)
+	if metadata.Next != nil {
+		t.Errorf("got next %+v on the last page; want none", metadata.Next)
+	}
+}
@@ -7,22 +7,204 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/lib/pq"
+	"universityforum.miguelavila.net/internals/markdown"
 	"universityforum.miguelavila.net/internals/validator"
 )
 
+var (
+	ErrNotAccepted = errors.New("reply not accepted")
+)
+
+// The kinds of forum
+const (
+	ForumKindDiscussion   = "discussion"
+	ForumKindQuestion     = "question"
+	ForumKindAnnouncement = "announcement"
+)
+
+// The publishing states of a forum. Drafts and scheduled forums are only
+// visible to their author
+const (
+	ForumStatusDraft     = "draft"
+	ForumStatusScheduled = "scheduled"
+	ForumStatusPublished = "published"
+)
+
 type Forum struct {
-	ID          int64     `json:"id"`
-	CreatedAt   time.Time `json:"-"`
-	Title       string    `json:"title"`
-	Description string    `json:"description,omitempty"`
-	Version     int32     `json:"version"`
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"-"`
+	Title     string    `json:"title"`
+	// Description holds the markdown source and DescriptionHTML the
+	// sanitized HTML rendered from it whenever the forum is saved
+	Description     string `json:"description,omitempty"`
+	DescriptionHTML string `json:"description_html,omitempty"`
+	// Mentions are the users mentioned in the description
+	Mentions Mentions `json:"mentions"`
+	// Mentioned holds the users mentioned for the first time by the last
+	// insert or update, who are due a notification
+	Mentioned       []*User      `json:"-"`
+	AuthorID        int64        `json:"-"`
+	Author          *UserSummary `json:"author,omitempty"`
+	CategoryID      int64        `json:"-"`
+	Category        *Category    `json:"category,omitempty"`
+	Tags            []string     `json:"tags"`
+	LikeCount       int64        `json:"like_count"`
+	LikedByMe       bool         `json:"liked_by_me"`
+	Reactions       []*Reaction  `json:"reactions,omitempty"`
+	Subscribed      bool         `json:"subscribed"`
+	Bookmarked      bool         `json:"bookmarked"`
+	Bookmark        *Bookmark    `json:"bookmark,omitempty"` // only in bookmark listings
+	Unread          bool         `json:"unread"`
+	UnreadCount     int64        `json:"unread_count"`
+	Poll            *Poll        `json:"poll,omitempty"`
+	Pinned          bool         `json:"pinned"`
+	Locked          bool         `json:"locked"`
+	Kind            string       `json:"kind"`
+	AcceptedReplyID int64        `json:"accepted_reply_id,omitempty"`
+	Status          string       `json:"status"`
+	PublishAt       *time.Time   `json:"publish_at,omitempty"`
+	PublishedAt     *time.Time   `json:"published_at,omitempty"`
+	DeletedAt       *time.Time   `json:"deleted_at,omitempty"`
+	DeletedBy       *UserSummary `json:"deleted_by,omitempty"`
+	Version         int32        `json:"version"`
+	// Only filled in when searching
+	Headline  string   `json:"headline,omitempty"`
+	Relevance *float64 `json:"relevance,omitempty"`
 }
 
 // define a ForumModel object that wraps a sql.DB connection pool
 type ForumModel struct {
 	DB *sql.DB
+	// noTrigrams is set once Suggest() finds that pg_trgm is missing
+	noTrigrams *atomic.Bool
+}
+
+// ForumCriteria holds the optional criteria used to narrow down a listing of
+// forums. Zero values are ignored
+type ForumCriteria struct {
+	Title string
+	// Tags must all be present on a forum, or at least one of them when
+	// AnyTag is set
+	Tags       []string
+	AnyTag     bool
+	CategoryID int64 // includes the sub categories
+	// Answered narrows the listing to question forums with or without an
+	// accepted answer
+	Answered *bool
+	// Status defaults to published. Only the viewer's own drafts and
+	// scheduled forums are listed
+	Status string
+	// Unread narrows the listing to the forums with something the viewer
+	// has not read
+	Unread bool
+	// Search is matched against the titles, descriptions and replies, in
+	// websearch_to_tsquery syntax: "quoted phrases", -exclude and OR
+	Search string
+	// AuthorID narrows the listing to the forums of one user
+	AuthorID int64
+}
+
+// forumLikes is the number of likes of a forum
+const forumLikes = `(SELECT COUNT(*) FROM forumslikes WHERE forumslikes.forums_id = forums.id)`
+
+// forumColumns is the select list shared by the forum queries. It has to be
+// kept in step with forumRow.dest()
+const forumColumns = `
+	forums.id, forums.created_at, forums.title, forums.description,
+	forums.description_html, forums.mentions,
+	COALESCE(forums.author_id, 0),
+	COALESCE((SELECT name FROM users WHERE users.id = forums.author_id), ''),
+	COALESCE(forums.category_id, 0),
+	COALESCE((SELECT name FROM categories WHERE categories.id = forums.category_id), ''),
+	COALESCE((SELECT path FROM categories WHERE categories.id = forums.category_id), ''),
+	ARRAY(SELECT tags.name FROM forums_tags
+	      INNER JOIN tags ON tags.id = forums_tags.tag_id
+	      WHERE forums_tags.forum_id = forums.id
+	      ORDER BY tags.name),
+	` + forumLikes + ` AS likes,
+	forums.deleted_at,
+	COALESCE(forums.deleted_by, 0),
+	COALESCE((SELECT name FROM users WHERE users.id = forums.deleted_by), ''),
+	forums.pinned, forums.locked, forums.kind,
+	COALESCE(forums.accepted_reply_id, 0),
+	forums.status, forums.publish_at, forums.published_at,
+	forums.version`
+
+// forumRow holds a forum while it is scanned along with the columns of the
+// related records
+type forumRow struct {
+	forum         Forum
+	authorName    string
+	categoryName  string
+	categoryPath  string
+	deletedAt     sql.NullTime
+	deletedByID   int64
+	deletedByName string
+	publishAt     sql.NullTime
+	publishedAt   sql.NullTime
+}
+
+// dest() returns the scan destinations matching forumColumns
+func (row *forumRow) dest() []interface{} {
+	return []interface{}{
+		&row.forum.ID,
+		&row.forum.CreatedAt,
+		&row.forum.Title,
+		&row.forum.Description,
+		&row.forum.DescriptionHTML,
+		&row.forum.Mentions,
+		&row.forum.AuthorID,
+		&row.authorName,
+		&row.forum.CategoryID,
+		&row.categoryName,
+		&row.categoryPath,
+		pq.Array(&row.forum.Tags),
+		&row.forum.LikeCount,
+		&row.deletedAt,
+		&row.deletedByID,
+		&row.deletedByName,
+		&row.forum.Pinned,
+		&row.forum.Locked,
+		&row.forum.Kind,
+		&row.forum.AcceptedReplyID,
+		&row.forum.Status,
+		&row.publishAt,
+		&row.publishedAt,
+		&row.forum.Version,
+	}
+}
+
+// result() fills in the embedded records and returns the forum. Forums
+// created before authorship was recorded have no author
+func (row *forumRow) result() *Forum {
+	forum := row.forum
+	if forum.AuthorID != 0 {
+		forum.Author = &UserSummary{ID: forum.AuthorID, Name: row.authorName}
+	}
+	if forum.CategoryID != 0 {
+		forum.Category = &Category{ID: forum.CategoryID, Name: row.categoryName, Path: row.categoryPath}
+	}
+	if forum.Tags == nil {
+		forum.Tags = []string{}
+	}
+	if row.deletedAt.Valid {
+		forum.DeletedAt = &row.deletedAt.Time
+	}
+	if row.deletedByID != 0 {
+		forum.DeletedBy = &UserSummary{ID: row.deletedByID, Name: row.deletedByName}
+	}
+	if row.publishAt.Valid {
+		forum.PublishAt = &row.publishAt.Time
+	}
+	if row.publishedAt.Valid {
+		forum.PublishedAt = &row.publishedAt.Time
+	}
+	return &forum
 }
 
 func ValidateForum(v *validator.Validator, forum *Forum) {
@@ -31,31 +213,91 @@ func ValidateForum(v *validator.Validator, forum *Forum) {
 	v.Check(len(forum.Title) <= 200, "title", "must not be more than 200 bytes long")
 
 	v.Check(forum.Description != "", "description", "must be provided")
-	v.Check(len(forum.Description) <= 2000, "description", "must not be more than 2000 bytes long")
+	v.Check(len(forum.Description) <= 10000, "description", "must not be more than 10000 bytes long")
+
+	v.Check(validator.In(forum.Kind, ForumKindDiscussion, ForumKindQuestion, ForumKindAnnouncement), "kind", "must be discussion, question or announcement")
+
+	v.Check(validator.In(forum.Status, ForumStatusDraft, ForumStatusScheduled, ForumStatusPublished), "status", "must be draft, scheduled or published")
+	v.Check(forum.Status != ForumStatusScheduled || forum.PublishAt != nil, "publish_at", "must be provided when the forum is scheduled")
+
+	ValidateTags(v, forum.Tags)
+	if forum.Poll != nil {
+		ValidatePoll(v, forum.Poll)
+	}
 }
 
-// Insert() allows us  to create a new Forum
+// Insert() allows us  to create a new Forum along with its tags and poll
 func (m ForumModel) Insert(forum *Forum) error {
 	query := `
-		INSERT INTO forums (title, description)
-		VALUES ($1, $2)
-		RETURNING id, created_at, version
+		INSERT INTO forums (title, description, description_html, mentions, author_id, category_id, kind,
+		                    status, publish_at, published_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0), $7,
+		        $8, $9, CASE WHEN $8 = 'published' THEN NOW() END)
+		RETURNING id, created_at, version, published_at
 	`
-
-	// Collect the data fields into a slice
-	args := []interface{}{
-		forum.Title, forum.Description,
+	// Render the description once so reads do not have to
+	html, err := markdown.Render(forum.Description)
+	if err != nil {
+		return err
 	}
+	forum.DescriptionHTML = html
+
 	// Create a context
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	// Cleanup to prevent memory leaks
 	defer cancel()
 
-	return m.DB.QueryRowContext(ctx, query, args...).Scan(&forum.ID, &forum.CreatedAt, &forum.Version)
+	// The forum, its tags, mentions and poll are written in a single
+	// transaction
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
+	mentions, users, err := resolveMentions(ctx, tx, forum.Description)
+	if err != nil {
+		return err
+	}
+	forum.Mentions = mentions
+
+	// Collect the data fields into a slice
+	args := []interface{}{
+		forum.Title, forum.Description, forum.DescriptionHTML, forum.Mentions, forum.AuthorID, forum.CategoryID, forum.Kind,
+		forum.Status, forum.PublishAt,
+	}
+	var publishedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&forum.ID, &forum.CreatedAt, &forum.Version, &publishedAt)
+	if err != nil {
+		return err
+	}
+	if publishedAt.Valid {
+		forum.PublishedAt = &publishedAt.Time
+	}
+	forum.Mentioned, err = recordMentions(ctx, tx, forum.ID, 0, forum.AuthorID, users)
+	if err != nil {
+		return err
+	}
+	// The author follows the replies to their forum
+	err = autoSubscribe(ctx, tx, forum.ID, forum.AuthorID)
+	if err != nil {
+		return err
+	}
+	err = setForumTags(ctx, tx, forum.ID, forum.Tags)
+	if err != nil {
+		return err
+	}
+	if forum.Poll != nil {
+		err = insertPoll(ctx, tx, forum.ID, forum.Poll)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
-// Get() allows us to retrieve a specific Forum
+// Get() allows us to retrieve a specific Forum. Forums in the trash are
+// not found
 func (m ForumModel) Get(id int64) (*Forum, error) {
 	// Ensure that there is a valid id
 	if id < 1 {
@@ -63,24 +305,19 @@ func (m ForumModel) Get(id int64) (*Forum, error) {
 	}
 	// Create the query
 	query := `
-		SELECT id, created_at, title, description, version
+		SELECT ` + forumColumns + `
 		FROM forums
-		WHERE id = $1
+		WHERE forums.id = $1
+		AND forums.deleted_at IS NULL
 	`
-	// Declare a Forum variable to hold the returned data
-	var forum Forum
+	// Declare a forumRow variable to hold the returned data
+	var row forumRow
 	// Create a context
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	// Cleanup to prevent memory leaks
 	defer cancel()
 	// Execute the query using QueryRow()
-	err := m.DB.QueryRowContext(ctx, query, id).Scan(
-		&forum.ID,
-		&forum.CreatedAt,
-		&forum.Title,
-		&forum.Description,
-		&forum.Version,
-	)
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(row.dest()...)
 	// Handle any errors
 	if err != nil {
 		// Check the type of error
@@ -92,34 +329,85 @@ func (m ForumModel) Get(id int64) (*Forum, error) {
 		}
 	}
 	// Success
-	return &forum, nil
+	return row.result(), nil
 }
 
-// Update() allows us to edit/alter a specific Forum
+// GetVisible() is Get() on behalf of a user. Drafts and scheduled forums are
+// not found unless the user is their author
+func (m ForumModel) GetVisible(id, userID int64) (*Forum, error) {
+	forum, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if forum.Status != ForumStatusPublished && forum.AuthorID != userID {
+		return nil, ErrRecordNotFound
+	}
+	return forum, nil
+}
+
+// Update() allows us to edit/alter a specific Forum and its tags. The
+// version being replaced is kept in the revision history along with
+// editorID, the user making the change. The status and publish time of a
+// forum that stays hidden are saved too; ErrEditConflict is returned if it
+// was published in the meantime. Publishing is left to Publish()
 // Optimistic locking (version number)
-func (m ForumModel) Update(forum *Forum) error {
+func (m ForumModel) Update(forum *Forum, editorID int64) error {
 	// Create the query
 	query := `
 		UPDATE forums
-		SET title = $1, description = $2, version = version + 1
-		WHERE id = $3
-		AND version = $4
+		SET title = $1, description = $2, description_html = $3, mentions = $4,
+		    category_id = NULLIF($5, 0), version = version + 1,
+		    status = CASE WHEN $8 = 'published' THEN status ELSE $8 END,
+		    publish_at = CASE WHEN $8 = 'published' THEN publish_at ELSE $9 END
+		WHERE id = $6
+		AND version = $7
+		AND deleted_at IS NULL
+		AND ($8 = 'published' OR status <> 'published')
 		RETURNING version
 	`
-	args := []interface{}{
-		forum.Title,
-		forum.Description,
-		forum.ID,
-		forum.Version,
+	// Render the description once so reads do not have to
+	html, err := markdown.Render(forum.Description)
+	if err != nil {
+		return err
 	}
+	forum.DescriptionHTML = html
 
 	// Create a context
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	// Cleanup to prevent memory leaks
 	defer cancel()
 
+	// The forum, its tags and mentions are written in a single transaction
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	mentions, users, err := resolveMentions(ctx, tx, forum.Description)
+	if err != nil {
+		return err
+	}
+	forum.Mentions = mentions
+
+	args := []interface{}{
+		forum.Title,
+		forum.Description,
+		forum.DescriptionHTML,
+		forum.Mentions,
+		forum.CategoryID,
+		forum.ID,
+		forum.Version,
+		forum.Status,
+		forum.PublishAt,
+	}
+
+	err = saveForumRevision(ctx, tx, forum, editorID)
+	if err != nil {
+		return err
+	}
 	// Check for edit conflicts
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&forum.Version)
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&forum.Version)
 	if err != nil {
 		switch {
 		case errors.Is(err, sql.ErrNoRows):
@@ -128,19 +416,31 @@ func (m ForumModel) Update(forum *Forum) error {
 			return err
 		}
 	}
-	return nil
+	// Only the users who were not mentioned before are due a notification
+	forum.Mentioned, err = recordMentions(ctx, tx, forum.ID, 0, forum.AuthorID, users)
+	if err != nil {
+		return err
+	}
+	err = setForumTags(ctx, tx, forum.ID, forum.Tags)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// Delete() removes a specific Forum
-func (m ForumModel) Delete(id int64) error {
+// Delete() moves a specific Forum to the trash. userID is the user
+// deleting it
+func (m ForumModel) Delete(id int64, userID int64) error {
 	// Ensure that there is a valid id
 	if id < 1 {
 		return ErrRecordNotFound
 	}
 	// Create the delete query
 	query := `
-		DELETE FROM forums
+		UPDATE forums
+		SET deleted_at = NOW(), deleted_by = $2
 		WHERE id = $1
+		AND deleted_at IS NULL
 	`
 
 	// Create a context
@@ -149,7 +449,7 @@ func (m ForumModel) Delete(id int64) error {
 	defer cancel()
 
 	// Execute the query
-	result, err := m.DB.ExecContext(ctx, query, id)
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
 	if err != nil {
 		return err
 	}
@@ -166,22 +466,411 @@ func (m ForumModel) Delete(id int64) error {
 	return nil
 }
 
-// The GetAll() method retuns a list of all the forums sorted by id
-func (m ForumModel) GetAll(title string, filters Filters) ([]*Forum, Metadata, error) {
-	// Construct the query
+// Restore() takes a specific Forum out of the trash
+func (m ForumModel) Restore(id int64) error {
+	// Ensure that there is a valid id
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+	query := `
+		UPDATE forums
+		SET deleted_at = NULL, deleted_by = NULL
+		WHERE id = $1
+		AND deleted_at IS NOT NULL
+	`
+
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	// The forum does not exist or is not in the trash
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// Purge() permanently removes the forums that have been in the trash for
+// longer than the retention period and returns how many were removed. Their
+// replies and likes are removed with them, and the reputation of the authors
+// of those replies is brought up to date in the same transaction
+func (m ForumModel) Purge(retention time.Duration) (int64, error) {
+	// Purging may touch many rows so allow it more time
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// The votes and accepted answers of the replies going away no longer
+	// count towards the reputation of their authors
+	cutoff := time.Now().Add(-retention)
+	query := `
+		SELECT ARRAY(
+			SELECT DISTINCT replies.users_id
+			FROM replies
+			INNER JOIN forums ON forums.id = replies.forums_id
+			WHERE forums.deleted_at < $1
+			AND replies.users_id IS NOT NULL
+		)
+	`
+	var authors []int64
+	err = tx.QueryRowContext(ctx, query, cutoff).Scan(pq.Array(&authors))
+	if err != nil {
+		return 0, err
+	}
+
+	query = `
+		DELETE FROM forums
+		WHERE deleted_at < $1
+	`
+	result, err := tx.ExecContext(ctx, query, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	// recomputeReputation() would go over every user given none
+	if len(authors) > 0 {
+		err = recomputeReputation(ctx, tx, authors)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return purged, tx.Commit()
+}
+
+// RefreshSearchVectors() rebuilds the search documents of up to batchSize
+// of the forums whose replies changed, and returns how many changes it went
+// through. Replies only queue their forum, so that writing a reply does not
+// go over the whole thread or lock the forum. Several servers can refresh
+// at the same time without taking the same changes
+func (m ForumModel) RefreshSearchVectors(batchSize int) (int64, error) {
+	query := `
+		WITH queued AS (
+			DELETE FROM forum_search_refreshes
+			WHERE id IN (
+				SELECT id FROM forum_search_refreshes
+				ORDER BY id
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING forum_id
+		), refreshed AS (
+			UPDATE forums
+			SET search_vector = forum_search_vector(forums.id, forums.title, forums.description)
+			WHERE forums.id IN (SELECT forum_id FROM queued)
+		)
+		SELECT COUNT(*) FROM queued
+	`
+	// Rebuilding long threads may take a while
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var count int64
+	err := m.DB.QueryRowContext(ctx, query, batchSize).Scan(&count)
+	return count, err
+}
+
+// UpdateState() saves whether a forum is pinned or locked and its kind. These
+// are moderation settings rather than content, so the version is left alone
+// and no revision is kept
+func (m ForumModel) UpdateState(forum *Forum) error {
+	query := `
+		UPDATE forums
+		SET pinned = $1, locked = $2, kind = $3
+		WHERE id = $4
+		AND deleted_at IS NULL
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, forum.Pinned, forum.Locked, forum.Kind, forum.ID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	// The forum was deleted in the meantime
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	return nil
+}
+
+// Publish() makes a draft or scheduled forum visible to everyone. It reports
+// whether this call published the forum, so that whoever publishes it, the
+// author or the scheduler, is the only one to announce it. The users
+// mentioned in the forum are loaded into Mentioned as none of them has been
+// notified yet
+func (m ForumModel) Publish(forum *Forum) (bool, error) {
+	query := `
+		UPDATE forums
+		SET status = 'published', published_at = NOW()
+		WHERE id = $1
+		AND status <> 'published'
+		AND deleted_at IS NULL
+		RETURNING published_at
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var publishedAt time.Time
+	err = tx.QueryRowContext(ctx, query, forum.ID).Scan(&publishedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return false, nil
+		default:
+			return false, err
+		}
+	}
+
+	query = `
+		SELECT users.id, users.name, users.email
+		FROM mentions
+		INNER JOIN users ON users.id = mentions.user_id
+		WHERE mentions.forum_id = $1
+		AND users.activated
+		ORDER BY users.id
+	`
+	rows, err := tx.QueryContext(ctx, query, forum.ID)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	mentioned := []*User{}
+	for rows.Next() {
+		var user User
+		err := rows.Scan(&user.ID, &user.Name, &user.Email)
+		if err != nil {
+			return false, err
+		}
+		mentioned = append(mentioned, &user)
+	}
+	if err = rows.Err(); err != nil {
+		return false, err
+	}
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+
+	forum.Status = ForumStatusPublished
+	forum.PublishedAt = &publishedAt
+	forum.Mentioned = mentioned
+	return true, nil
+}
+
+// GetDue() returns the ids of the scheduled forums whose publish time has
+// passed, oldest first
+func (m ForumModel) GetDue() ([]int64, error) {
+	query := `
+		SELECT id
+		FROM forums
+		WHERE status = 'scheduled'
+		AND publish_at <= NOW()
+		AND deleted_at IS NULL
+		ORDER BY publish_at, id
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// AcceptReply() marks a reply as the accepted answer of a forum, replacing
+// the one accepted before. The reputation of both authors is brought up to
+// date in the same transaction
+func (m ForumModel) AcceptReply(forumID, replyID int64) error {
+	return m.setAcceptedReply(forumID, replyID, replyID)
+}
+
+// UnacceptReply() clears the accepted answer of a forum. It returns
+// ErrNotAccepted when the accepted answer is not the given reply
+func (m ForumModel) UnacceptReply(forumID, replyID int64) error {
+	return m.setAcceptedReply(forumID, replyID, 0)
+}
+
+// setAcceptedReply() sets the accepted answer of a forum to accepted, 0 for
+// none. When accepted is 0 the answer is only cleared if it is replyID
+func (m ForumModel) setAcceptedReply(forumID, replyID, accepted int64) error {
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the forum and find the answer being replaced
+	query := `
+		SELECT COALESCE(accepted_reply_id, 0)
+		FROM forums
+		WHERE id = $1
+		AND deleted_at IS NULL
+		FOR UPDATE
+	`
+	var previous int64
+	err = tx.QueryRowContext(ctx, query, forumID).Scan(&previous)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+	// Another reply is accepted, or none, so there is nothing to clear
+	if accepted == 0 && previous != replyID {
+		return ErrNotAccepted
+	}
+
+	query = `
+		UPDATE forums
+		SET accepted_reply_id = NULLIF($2, 0)
+		WHERE id = $1
+	`
+	_, err = tx.ExecContext(ctx, query, forumID, accepted)
+	if err != nil {
+		return err
+	}
+
+	// Bring the reputation of the authors up to date
+	query = `
+		SELECT ARRAY(SELECT DISTINCT users_id FROM replies WHERE id IN ($1, $2))
+	`
+	var authors []int64
+	err = tx.QueryRowContext(ctx, query, previous, replyID).Scan(pq.Array(&authors))
+	if err != nil {
+		return err
+	}
+	if len(authors) > 0 {
+		err = recomputeReputation(ctx, tx, authors)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// The GetAll() method retuns a list of the forums matching the criteria.
+// Pinned forums always come first, whatever the sort. userID is the user
+// viewing the list and is used to fill in LikedByMe, Bookmarked and the
+// unread state. A forum is unread when someone else replied after the
+// viewer's read position, or when the viewer never opened someone else's
+// forum. The unread counts are part of the listing query. The listing can be
+// continued from a cursor
+func (m ForumModel) GetAll(criteria ForumCriteria, userID int64, filters Filters) ([]*Forum, Metadata, error) {
+	keys := forumKeyset(filters)
+	args := []interface{}{
+		criteria.Title, filters.limit() + 1, filters.offset(), userID,
+		pq.Array(NormalizeTags(criteria.Tags)), criteria.AnyTag, criteria.CategoryID,
+		criteria.Answered, criteria.Status, criteria.Unread, criteria.Search,
+		criteria.AuthorID,
+	}
+	after, afterArgs := keys.after(filters.Cursor, len(args)+1)
+	args = append(args, afterArgs...)
+	// Construct the query. One more forum than the page holds is fetched to
+	// tell whether there is a next page
 	query := fmt.Sprintf(`
-		SELECT COUNT(*) OVER(), id, created_at, title, description,
-		       version
+		SELECT %s, %s,
+		       EXISTS (SELECT 1 FROM forumslikes WHERE forums_id = forums.id AND users_id = $4),
+		       EXISTS (SELECT 1 FROM bookmarks WHERE forum_id = forums.id AND user_id = $4),
+		       unread.count,
+		       unread.count > 0 OR (forum_reads.user_id IS NULL AND forums.author_id IS DISTINCT FROM $4),
+		       CASE WHEN $11 = '' THEN '' ELSE %s END,
+		       ts_rank_cd(forums.search_vector, search_query) AS relevance,
+		       %s
 		FROM forums
-		WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '')
-		ORDER BY %s %s, id ASC
-		LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortOrder())
+		CROSS JOIN websearch_to_tsquery('simple', $11) AS search_query
+		LEFT JOIN forum_reads ON forum_reads.forum_id = forums.id AND forum_reads.user_id = $4
+		CROSS JOIN LATERAL (
+			SELECT COUNT(*) AS count
+			FROM replies
+			WHERE replies.forums_id = forums.id
+			AND replies.id > COALESCE(forum_reads.last_read_reply_id, 0)
+			AND replies.users_id IS DISTINCT FROM $4
+		) AS unread
+		WHERE forums.deleted_at IS NULL
+		AND (to_tsvector('simple', forums.title) @@ plainto_tsquery('simple', $1) OR $1 = '')
+		AND (forums.search_vector @@ search_query OR $11 = '')
+		AND (cardinality($5::text[]) = 0 OR (
+			SELECT COUNT(*) FROM forums_tags
+			INNER JOIN tags ON tags.id = forums_tags.tag_id
+			WHERE forums_tags.forum_id = forums.id AND tags.name = ANY($5)
+		) >= CASE WHEN $6 THEN 1 ELSE cardinality($5::text[]) END)
+		AND ($7 = 0 OR forums.category_id IN (
+			WITH RECURSIVE subcategories AS (
+				SELECT id FROM categories WHERE id = $7
+				UNION ALL
+				SELECT categories.id FROM categories
+				INNER JOIN subcategories ON categories.parent_id = subcategories.id
+			)
+			SELECT id FROM subcategories
+		))
+		AND ($8::boolean IS NULL OR (
+			forums.kind = 'question' AND (forums.accepted_reply_id IS NOT NULL) = $8
+		))
+		AND forums.status = COALESCE(NULLIF($9, ''), 'published')
+		AND (forums.status = 'published' OR forums.author_id = $4)
+		AND ($12 = 0 OR forums.author_id = $12)
+		AND (NOT $10 OR unread.count > 0 OR (forum_reads.user_id IS NULL AND forums.author_id IS DISTINCT FROM $4))
+		AND %s
+		ORDER BY %s
+		LIMIT $2 OFFSET $3`, filters.total(), forumColumns, searchHeadline(forumSnippet), keys.values(), after, keys.orderBy())
 
 	// Create a 3-second-timout context
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 	// Execute the query
-	args := []interface{}{title, filters.limit(), filters.offset()}
 	rows, err := m.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, Metadata{}, err
@@ -191,29 +880,191 @@ func (m ForumModel) GetAll(title string, filters Filters) ([]*Forum, Metadata, e
 	totalRecords := 0
 	// Initialize an empty slice to hold the Forum data
 	forums := []*Forum{}
+	// The sort key values of each forum, for the next cursor
+	positions := [][]string{}
 	// Iterate over the rows in the resultset
 	for rows.Next() {
-		var forum Forum
+		var row forumRow
+		var likedByMe, bookmarked, unread bool
+		var unreadCount int64
+		var headline string
+		var relevance float64
+		var position pq.StringArray
 		// Scan the values from the row into forum
-		err := rows.Scan(
-			&totalRecords,
-			&forum.ID,
-			&forum.CreatedAt,
-			&forum.Title,
-			&forum.Description,
-			&forum.Version,
-		)
+		dest := append([]interface{}{&totalRecords}, row.dest()...)
+		err := rows.Scan(append(dest, &likedByMe, &bookmarked, &unreadCount, &unread, &headline, &relevance, &position)...)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
+		forum := row.result()
+		forum.Headline = headline
+		if criteria.Search != "" {
+			forum.Relevance = &relevance
+		}
+		forum.LikedByMe = likedByMe
+		forum.Bookmarked = bookmarked
+		forum.UnreadCount = unreadCount
+		forum.Unread = unread
 		// Add the Forum to our slice
-		forums = append(forums, &forum)
+		forums = append(forums, forum)
+		positions = append(positions, position)
 	}
 	// Check for errors after looping through the resultset
 	if err = rows.Err(); err != nil {
 		return nil, Metadata{}, err
 	}
-	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	more := len(forums) > filters.PageSize
+	var last []string
+	if more {
+		forums = forums[:filters.PageSize]
+		last = positions[filters.PageSize-1]
+	}
+	metadata := calculateKeysetMetadata(totalRecords, more, last, filters)
 	// Return the slice of Forums
 	return forums, metadata, nil
 }
+
+// ValidateForumFilters() checks the filters of a forum listing, including
+// its cursor
+func ValidateForumFilters(v *validator.Validator, f Filters) {
+	if ValidateFilters(v, f); v.Valid() {
+		validateCursor(v, f, forumKeyset(f))
+	}
+}
+
+// forumKeyset() is the order of a forum listing. Pinned forums always come
+// first; sorted by relevance, the best match comes next
+func forumKeyset(filters Filters) keyset {
+	pinned := sortKey{expr: "forums.pinned", desc: true}
+	id := sortKey{expr: "forums.id"}
+	if filters.Sort == "relevance" {
+		return keyset{pinned, {expr: "ts_rank_cd(forums.search_vector, search_query)", desc: true}, id}
+	}
+	column := map[string]string{
+		"id":    "forums.id",
+		"title": "forums.title",
+		"likes": forumLikes,
+	}[filters.sortColumn()]
+	keys := keyset{pinned, {expr: column, desc: filters.sortOrder() == "DESC"}}
+	// The id breaks the ties of the other columns
+	if column != id.expr {
+		keys = append(keys, id)
+	}
+	return keys
+}
+
+// The GetTrash() method returns a list of the forums in the trash
+func (m ForumModel) GetTrash(filters Filters) ([]*Forum, Metadata, error) {
+	// Construct the query
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), %s
+		FROM forums
+		WHERE forums.deleted_at IS NOT NULL
+		ORDER BY %s %s, id ASC
+		LIMIT $1 OFFSET $2`, forumColumns, filters.sortColumn(), filters.sortOrder())
+
+	// Create a 3-second-timout context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	rows, err := m.DB.QueryContext(ctx, query, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	// Close the resultset
+	defer rows.Close()
+	totalRecords := 0
+	forums := []*Forum{}
+	for rows.Next() {
+		var row forumRow
+		err := rows.Scan(append([]interface{}{&totalRecords}, row.dest()...)...)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		forums = append(forums, row.result())
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return forums, metadata, nil
+}
+
+// A Suggestion is a forum title offered while a title is being typed.
+// Similarity is left out when pg_trgm is not installed
+type Suggestion struct {
+	ID         int64    `json:"id"`
+	Title      string   `json:"title"`
+	Similarity *float64 `json:"similarity,omitempty"`
+}
+
+// Suggest() returns the titles of up to limit published forums that look
+// like query, the closest first. Misspellings are tolerated by comparing
+// trigrams, which needs the pg_trgm extension; without it only the titles
+// containing query are found. A missing pg_trgm is only found out once, the
+// server has to be restarted when it is installed
+func (m ForumModel) Suggest(query string, limit int) ([]*Suggestion, error) {
+	// query <% title matches when query is close to some part of the title,
+	// which suits a title that is still being typed. It is served by the
+	// forums_title_trgm_idx index
+	trigramQuery := `
+		SELECT id, title, word_similarity($1, title) AS similarity
+		FROM forums
+		WHERE $1 <% title
+		AND deleted_at IS NULL
+		AND status = 'published'
+		ORDER BY similarity DESC, id DESC
+		LIMIT $2
+	`
+	// The titles starting with query come first
+	substringQuery := `
+		SELECT id, title
+		FROM forums
+		WHERE title ILIKE '%' || $1::text || '%'
+		AND deleted_at IS NULL
+		AND status = 'published'
+		ORDER BY title ILIKE $1::text || '%' DESC, length(title), id DESC
+		LIMIT $2
+	`
+	// Create a 3-second-timout context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var rows *sql.Rows
+	var err error
+	trigrams := !m.noTrigrams.Load()
+	if trigrams {
+		rows, err = m.DB.QueryContext(ctx, trigramQuery, query, limit)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code.Name() == "undefined_function" {
+			// The <% operator and word_similarity() come with pg_trgm
+			m.noTrigrams.Store(true)
+			trigrams = false
+		}
+	}
+	if !trigrams {
+		pattern := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(query)
+		rows, err = m.DB.QueryContext(ctx, substringQuery, pattern, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suggestions := []*Suggestion{}
+	for rows.Next() {
+		var suggestion Suggestion
+		dest := []interface{}{&suggestion.ID, &suggestion.Title}
+		if trigrams {
+			dest = append(dest, &suggestion.Similarity)
+		}
+		err := rows.Scan(dest...)
+		if err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, &suggestion)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
@@ -0,0 +1,72 @@
+// Filename : internal/data/likes.go
+
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// define a LikeModel object that wraps a sql.DB connection pool
+type LikeModel struct {
+	DB *sql.DB
+}
+
+// Insert() records that a user likes a forum and reports whether the like is
+// new. Liking a forum twice is a no-op
+func (m LikeModel) Insert(userID, forumID int64) (bool, error) {
+	query := `
+		INSERT INTO forumslikes (users_id, forums_id)
+		VALUES ($1, $2)
+		ON CONFLICT (users_id, forums_id) DO NOTHING
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, forumID)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// Delete() removes the like of a user from a forum. Removing a like that does
+// not exist is a no-op
+func (m LikeModel) Delete(userID, forumID int64) error {
+	query := `
+		DELETE FROM forumslikes
+		WHERE users_id = $1 AND forums_id = $2
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID, forumID)
+	return err
+}
+
+// Exists() reports whether a user likes a forum
+func (m LikeModel) Exists(userID, forumID int64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM forumslikes
+			WHERE users_id = $1 AND forums_id = $2
+		)
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	var exists bool
+	err := m.DB.QueryRowContext(ctx, query, userID, forumID).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,178 @@
+// Filename : internal/data/mentions.go
+
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// mentionRX matches "@handle" when it is not part of a word or an email
+// address. The handle is the first group
+var mentionRX = regexp.MustCompile(`(?:^|[^\w@.])@([A-Za-z0-9][A-Za-z0-9._-]{0,63})`)
+
+// A Mention is an "@handle" in a text that was resolved to a user. Start and
+// End are byte offsets into the markdown source. A handle is the username of
+// the user, in lower case
+type Mention struct {
+	UserID int64  `json:"user_id"`
+	Handle string `json:"handle"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+}
+
+// Mentions is stored as a jsonb column
+type Mentions []Mention
+
+// Value() makes Mentions a driver.Valuer
+func (m Mentions) Value() (driver.Value, error) {
+	if m == nil {
+		return "[]", nil
+	}
+	js, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(js), nil
+}
+
+// Scan() makes Mentions a sql.Scanner
+func (m *Mentions) Scan(src interface{}) error {
+	*m = Mentions{}
+	switch src := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(src, m)
+	case string:
+		return json.Unmarshal([]byte(src), m)
+	default:
+		return fmt.Errorf("cannot scan %T into Mentions", src)
+	}
+}
+
+// A mentionSpan is an "@handle" found in a text, before it is resolved.
+// start is the offset of the "@", end the offset after the handle
+type mentionSpan struct {
+	handle     string
+	start, end int
+}
+
+// findMentions() returns the "@handle"s of text in order, with their handles
+// in lower case
+func findMentions(text string) []mentionSpan {
+	spans := []mentionSpan{}
+	for _, match := range mentionRX.FindAllStringSubmatchIndex(text, -1) {
+		// A trailing dot ends the sentence rather than the handle
+		handle := strings.TrimRight(text[match[2]:match[3]], ".")
+		spans = append(spans, mentionSpan{
+			handle: strings.ToLower(handle),
+			start:  match[2] - 1,
+			end:    match[2] + len(handle),
+		})
+	}
+	return spans
+}
+
+// resolveMentions() finds the "@handle"s in text and resolves them to
+// activated users by username. The users that were found are returned by id
+// so they can be notified
+func resolveMentions(ctx context.Context, tx *sql.Tx, text string) (Mentions, map[int64]*User, error) {
+	mentions := Mentions{}
+	users := make(map[int64]*User)
+
+	spans := findMentions(text)
+	if len(spans) == 0 {
+		return mentions, users, nil
+	}
+	handles := make([]string, 0, len(spans))
+	for _, span := range spans {
+		handles = append(handles, span.handle)
+	}
+
+	query := `
+		SELECT id, name, username, email, lower(username::text)
+		FROM users
+		WHERE activated
+		AND username = ANY($1::citext[])
+	`
+	rows, err := tx.QueryContext(ctx, query, pq.Array(handles))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	byHandle := make(map[string]*User)
+	for rows.Next() {
+		var user User
+		var handle string
+		err := rows.Scan(&user.ID, &user.Name, &user.Username, &user.Email, &handle)
+		if err != nil {
+			return nil, nil, err
+		}
+		byHandle[handle] = &user
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	for _, span := range spans {
+		user, ok := byHandle[span.handle]
+		if !ok {
+			continue
+		}
+		users[user.ID] = user
+		mentions = append(mentions, Mention{
+			UserID: user.ID,
+			Handle: span.handle,
+			Start:  span.start,
+			End:    span.end,
+		})
+	}
+	return mentions, users, nil
+}
+
+// recordMentions() stores who is mentioned by a forum or a reply (forumID or
+// replyID is 0) and returns the users mentioned there for the first time.
+// Authors mentioning themselves are not recorded
+func recordMentions(ctx context.Context, tx *sql.Tx, forumID, replyID, authorID int64, users map[int64]*User) ([]*User, error) {
+	ids := []int64{}
+	for id := range users {
+		if id != authorID {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	query := `
+		INSERT INTO mentions (forum_id, reply_id, user_id)
+		SELECT NULLIF($1, 0), NULLIF($2, 0), unnest($3::bigint[])
+		ON CONFLICT DO NOTHING
+		RETURNING user_id
+	`
+	rows, err := tx.QueryContext(ctx, query, forumID, replyID, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	mentioned := []*User{}
+	for rows.Next() {
+		var id int64
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		mentioned = append(mentioned, users[id])
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return mentioned, nil
+}
@@ -0,0 +1,75 @@
+// Filename: internal/data/mentions_test.go
+
+package data
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []mentionSpan
+	}{
+		{"none", "no mentions here", []mentionSpan{}},
+		{"start of text", "@alice hi", []mentionSpan{{"alice", 0, 6}}},
+		{"after a space", "hi @alice", []mentionSpan{{"alice", 3, 9}}},
+		{"lower cased", "hi @Alice", []mentionSpan{{"alice", 3, 9}}},
+		{"several", "@a and @b.c", []mentionSpan{{"a", 0, 2}, {"b.c", 7, 11}}},
+		{"end of sentence", "thanks @bob.", []mentionSpan{{"bob", 7, 11}}},
+		{"dots at the end", "see @bob...", []mentionSpan{{"bob", 4, 8}}},
+		{"punctuation before", "(@carol) and @dave,", []mentionSpan{{"carol", 1, 7}, {"dave", 13, 18}}},
+		{"dashes and underscores", "@a-b_c", []mentionSpan{{"a-b_c", 0, 6}}},
+		{"email address", "mail alice@example.com", []mentionSpan{}},
+		{"inside a word", "foo@bar", []mentionSpan{}},
+		{"double at", "@@alice", []mentionSpan{}},
+		{"after a dot", "x.@alice", []mentionSpan{}},
+		{"no handle", "@ alone", []mentionSpan{}},
+		{"must start with a letter or digit", "@_alice @.bob", []mentionSpan{}},
+		{"multibyte text before", "héllo @zoë", []mentionSpan{{"zo", 7, 10}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findMentions(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v; want %v", got, tt.want)
+			}
+			// The spans cover the "@handle" in the source
+			for _, span := range got {
+				if tt.text[span.start] != '@' {
+					t.Errorf("span %v does not start at an @", span)
+				}
+			}
+		})
+	}
+}
+
+func TestMentionHandleLength(t *testing.T) {
+	handle := "a123456789012345678901234567890123456789012345678901234567890123"
+	if got := findMentions("@" + handle + "xyz"); len(got) != 1 || got[0].handle != handle {
+		t.Errorf("got %v; want the first 64 characters", got)
+	}
+}
+
+func TestMentionsValue(t *testing.T) {
+	value, err := Mentions(nil).Value()
+	if err != nil || value != "[]" {
+		t.Errorf("got %v, %v; want []", value, err)
+	}
+	var mentions Mentions
+	err = mentions.Scan([]byte(`[{"user_id":1,"handle":"alice","start":0,"end":6}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Mentions{{UserID: 1, Handle: "alice", Start: 0, End: 6}}); !reflect.DeepEqual(mentions, want) {
+		t.Errorf("got %v; want %v", mentions, want)
+	}
+	if err = mentions.Scan(nil); err != nil || len(mentions) != 0 {
+		t.Errorf("got %v, %v scanning NULL; want no mentions", mentions, err)
+	}
+	if err = mentions.Scan(42); err == nil {
+		t.Error("got no error scanning an int")
+	}
+}
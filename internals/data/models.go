@@ -5,6 +5,7 @@ package data
 import (
 	"database/sql"
 	"errors"
+	"sync/atomic"
 )
 
 var (
@@ -14,18 +15,46 @@ var (
 
 // A wrapper for out data models
 type Models struct {
-	Forum       ForumModel
-	Permissions PermissionModel
-	Tokens      TokenModel
-	User        UserModel
+	Attachments   AttachmentModel
+	Bookmarks     BookmarkModel
+	Category      CategoryModel
+	Forum         ForumModel
+	Likes         LikeModel
+	Notifications NotificationModel
+	Permissions   PermissionModel
+	Polls         PollModel
+	Reactions     ReactionModel
+	Reads         ReadModel
+	Reply         ReplyModel
+	Revisions     RevisionModel
+	Search        SearchModel
+	Subscriptions SubscriptionModel
+	Tags          TagModel
+	Tokens        TokenModel
+	User          UserModel
+	Votes         VoteModel
 }
 
 // NewModels() allows us to create new models
 func NewModels(db *sql.DB) *Models {
 	return &Models{
-		Forum:       ForumModel{DB: db},
-		Permissions: PermissionModel{DB: db},
-		Tokens:      TokenModel{DB: db},
-		User:        UserModel{DB: db},
+		Attachments:   AttachmentModel{DB: db},
+		Bookmarks:     BookmarkModel{DB: db},
+		Category:      CategoryModel{DB: db},
+		Forum:         ForumModel{DB: db, noTrigrams: new(atomic.Bool)},
+		Likes:         LikeModel{DB: db},
+		Notifications: NotificationModel{DB: db},
+		Permissions:   PermissionModel{DB: db},
+		Polls:         PollModel{DB: db},
+		Reactions:     ReactionModel{DB: db},
+		Reads:         ReadModel{DB: db},
+		Reply:         ReplyModel{DB: db},
+		Revisions:     RevisionModel{DB: db},
+		Search:        SearchModel{DB: db},
+		Subscriptions: SubscriptionModel{DB: db},
+		Tags:          TagModel{DB: db},
+		Tokens:        TokenModel{DB: db},
+		User:          UserModel{DB: db},
+		Votes:         VoteModel{DB: db},
 	}
 }
@@ -0,0 +1,171 @@
+// Filename : internal/data/notifications.go
+
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// The types of notification
+const (
+	NotificationReply      = "reply"
+	NotificationMention    = "mention"
+	NotificationLike       = "like"
+	NotificationModeration = "moderation"
+	NotificationPublished  = "published"
+)
+
+// A Notification is an item of the in-app inbox of a user
+type Notification struct {
+	ID        int64           `json:"id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	Read      bool            `json:"read"`
+	ReadAt    *time.Time      `json:"read_at,omitempty"`
+}
+
+// define a NotificationModel object that wraps a sql.DB connection pool
+type NotificationModel struct {
+	DB *sql.DB
+}
+
+// Insert() adds the same notification to the inbox of each of the users and
+// returns the new notifications by user id
+func (m NotificationModel) Insert(userIDs []int64, notificationType string, payload interface{}) (map[int64]*Notification, error) {
+	notifications := make(map[int64]*Notification)
+	if len(userIDs) == 0 {
+		return notifications, nil
+	}
+	js, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		INSERT INTO notifications (user_id, type, payload)
+		SELECT unnest($1::bigint[]), $2, $3
+		RETURNING id, user_id, created_at
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(userIDs), notificationType, string(js))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		notification := Notification{Type: notificationType, Payload: js}
+		var userID int64
+		err := rows.Scan(&notification.ID, &userID, &notification.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		notifications[userID] = &notification
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// The GetAll() method returns a page of the inbox of a user, only the
+// unread notifications when unreadOnly is set
+func (m NotificationModel) GetAll(userID int64, unreadOnly bool, filters Filters) ([]*Notification, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), id, created_at, type, payload, read_at
+		FROM notifications
+		WHERE user_id = $1
+		AND (read_at IS NULL OR NOT $2)
+		ORDER BY %s %s, id DESC
+		LIMIT $3 OFFSET $4`, filters.sortColumn(), filters.sortOrder())
+
+	// Create a 3-second-timout context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, unreadOnly, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	notifications := []*Notification{}
+	for rows.Next() {
+		var notification Notification
+		var payload []byte
+		var readAt sql.NullTime
+		err := rows.Scan(
+			&totalRecords,
+			&notification.ID,
+			&notification.CreatedAt,
+			&notification.Type,
+			&payload,
+			&readAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		notification.Payload = payload
+		if readAt.Valid {
+			notification.Read = true
+			notification.ReadAt = &readAt.Time
+		}
+		notifications = append(notifications, &notification)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return notifications, metadata, nil
+}
+
+// MarkRead() marks notifications of a user as read and returns how many
+// were unread. With all set every notification of the user is marked and
+// ids is ignored
+func (m NotificationModel) MarkRead(userID int64, ids []int64, all bool) (int64, error) {
+	query := `
+		UPDATE notifications
+		SET read_at = NOW()
+		WHERE user_id = $1
+		AND read_at IS NULL
+		AND ($2 OR id = ANY($3))
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, all, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// CountUnread() returns the number of unread notifications of a user
+func (m NotificationModel) CountUnread(userID int64) (int64, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM notifications
+		WHERE user_id = $1
+		AND read_at IS NULL
+	`
+	var count int64
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(&count)
+	return count, err
+}
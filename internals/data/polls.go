@@ -0,0 +1,295 @@
+// Filename : internal/data/polls.go
+
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+var (
+	ErrAlreadyVoted = errors.New("already voted")
+	ErrPollClosed   = errors.New("poll closed")
+)
+
+// A Poll is attached to a forum when it is created. Votes are counted per
+// option; the counts, the voters and TotalVoters are left out while
+// ResultsHidden is set
+type Poll struct {
+	Question       string        `json:"question"`
+	MultipleChoice bool          `json:"multiple_choice"`
+	Anonymous      bool          `json:"anonymous"`
+	HideResults    bool          `json:"hide_results"`
+	ClosesAt       *time.Time    `json:"closes_at,omitempty"`
+	Closed         bool          `json:"closed"`
+	ResultsHidden  bool          `json:"results_hidden"`
+	TotalVoters    int64         `json:"total_voters"`
+	Options        []*PollOption `json:"options"`
+	MyVotes        []int64       `json:"my_votes"`
+}
+
+// A PollOption is one of the choices of a poll. Voters is only filled in on
+// polls that are not anonymous
+type PollOption struct {
+	ID     int64          `json:"id"`
+	Text   string         `json:"text"`
+	Votes  *int64         `json:"votes,omitempty"`
+	Voters []*UserSummary `json:"voters,omitempty"`
+}
+
+// define a PollModel object that wraps a sql.DB connection pool
+type PollModel struct {
+	DB *sql.DB
+}
+
+func ValidatePoll(v *validator.Validator, poll *Poll) {
+	v.Check(strings.TrimSpace(poll.Question) != "", "poll.question", "must be provided")
+	v.Check(len(poll.Question) <= 500, "poll.question", "must not be more than 500 bytes long")
+	v.Check(len(poll.Options) >= 2, "poll.options", "must contain at least 2 options")
+	v.Check(len(poll.Options) <= 20, "poll.options", "must not contain more than 20 options")
+	texts := make([]string, 0, len(poll.Options))
+	for _, option := range poll.Options {
+		v.Check(strings.TrimSpace(option.Text) != "", "poll.options", "must not contain empty options")
+		v.Check(len(option.Text) <= 200, "poll.options", "must not contain options more than 200 bytes long")
+		texts = append(texts, option.Text)
+	}
+	v.Check(validator.Unique(texts), "poll.options", "must not contain duplicate values")
+	if poll.ClosesAt != nil {
+		v.Check(poll.ClosesAt.After(time.Now()), "poll.closes_at", "must be in the future")
+	}
+	// The results are revealed when the poll closes
+	v.Check(!poll.HideResults || poll.ClosesAt != nil, "poll.closes_at", "must be provided to hide the results")
+}
+
+// ValidateVote() checks the options chosen on a ballot against the poll
+func ValidateVote(v *validator.Validator, poll *Poll, optionIDs []int64) {
+	v.Check(len(optionIDs) > 0, "option_ids", "must contain at least 1 option")
+	v.Check(poll.MultipleChoice || len(optionIDs) <= 1, "option_ids", "must contain a single option on this poll")
+	known := make(map[int64]bool)
+	for _, option := range poll.Options {
+		known[option.ID] = true
+	}
+	seen := make(map[int64]bool)
+	for _, id := range optionIDs {
+		v.Check(known[id], "option_ids", "must only contain options of this poll")
+		v.Check(!seen[id], "option_ids", "must not contain duplicate values")
+		seen[id] = true
+	}
+}
+
+// insertPoll() stores the poll of a new forum. It runs inside the
+// transaction of the forum insert
+func insertPoll(ctx context.Context, tx *sql.Tx, forumID int64, poll *Poll) error {
+	query := `
+		INSERT INTO polls (forum_id, question, multiple_choice, anonymous, hide_results, closes_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	args := []interface{}{
+		forumID, poll.Question, poll.MultipleChoice, poll.Anonymous, poll.HideResults, poll.ClosesAt,
+	}
+	_, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	// The options keep the order they were given in
+	for i, option := range poll.Options {
+		query = `
+			INSERT INTO poll_options (forum_id, position, text)
+			VALUES ($1, $2, $3)
+			RETURNING id
+		`
+		err = tx.QueryRowContext(ctx, query, forumID, i, option.Text).Scan(&option.ID)
+		if err != nil {
+			return err
+		}
+	}
+	poll.MyVotes = []int64{}
+	poll.ResultsHidden = poll.HideResults
+	return nil
+}
+
+// Get() returns the poll of a forum with its results as seen by the user
+func (m PollModel) Get(forumID int64, userID int64) (*Poll, error) {
+	query := `
+		SELECT question, multiple_choice, anonymous, hide_results, closes_at,
+		       closes_at IS NOT NULL AND closes_at <= NOW(),
+		       (SELECT COUNT(*) FROM poll_ballots WHERE poll_ballots.forum_id = polls.forum_id)
+		FROM polls
+		WHERE forum_id = $1
+	`
+	var poll Poll
+	var closesAt sql.NullTime
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, forumID).Scan(
+		&poll.Question,
+		&poll.MultipleChoice,
+		&poll.Anonymous,
+		&poll.HideResults,
+		&closesAt,
+		&poll.Closed,
+		&poll.TotalVoters,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	if closesAt.Valid {
+		poll.ClosesAt = &closesAt.Time
+	}
+	// The creator may ask for the results to be kept back until the poll
+	// closes
+	poll.ResultsHidden = poll.HideResults && !poll.Closed
+	if poll.ResultsHidden {
+		poll.TotalVoters = 0
+	}
+
+	// Fetch the options along with their counts
+	query = `
+		SELECT id, text,
+		       (SELECT COUNT(*) FROM poll_votes WHERE poll_votes.option_id = poll_options.id)
+		FROM poll_options
+		WHERE forum_id = $1
+		ORDER BY position
+	`
+	rows, err := m.DB.QueryContext(ctx, query, forumID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	options := make(map[int64]*PollOption)
+	for rows.Next() {
+		var option PollOption
+		var votes int64
+		err := rows.Scan(&option.ID, &option.Text, &votes)
+		if err != nil {
+			return nil, err
+		}
+		if !poll.ResultsHidden {
+			option.Votes = &votes
+		}
+		poll.Options = append(poll.Options, &option)
+		options[option.ID] = &option
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Fetch the options the user chose
+	query = `
+		SELECT ARRAY(SELECT option_id FROM poll_votes WHERE forum_id = $1 AND user_id = $2 ORDER BY option_id)
+	`
+	err = m.DB.QueryRowContext(ctx, query, forumID, userID).Scan(pq.Array(&poll.MyVotes))
+	if err != nil {
+		return nil, err
+	}
+
+	// Named polls list who voted for what
+	if poll.Anonymous || poll.ResultsHidden {
+		return &poll, nil
+	}
+	query = `
+		SELECT poll_votes.option_id, users.id, users.name
+		FROM poll_votes
+		INNER JOIN users ON users.id = poll_votes.user_id
+		WHERE poll_votes.forum_id = $1
+		ORDER BY users.name, users.id
+	`
+	voters, err := m.DB.QueryContext(ctx, query, forumID)
+	if err != nil {
+		return nil, err
+	}
+	defer voters.Close()
+	for voters.Next() {
+		var optionID int64
+		var voter UserSummary
+		err := voters.Scan(&optionID, &voter.ID, &voter.Name)
+		if err != nil {
+			return nil, err
+		}
+		if option, ok := options[optionID]; ok {
+			option.Voters = append(option.Voters, &voter)
+		}
+	}
+	if err = voters.Err(); err != nil {
+		return nil, err
+	}
+	return &poll, nil
+}
+
+// Vote() casts the ballot of a user. A user votes once per poll, which the
+// primary key of poll_ballots enforces; a second ballot returns
+// ErrAlreadyVoted
+func (m PollModel) Vote(forumID int64, userID int64, optionIDs []int64) error {
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	// The ballot and its choices are written in a single transaction
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO poll_ballots (forum_id, user_id)
+		SELECT forum_id, $2
+		FROM polls
+		WHERE forum_id = $1
+		AND (closes_at IS NULL OR closes_at > NOW())
+		ON CONFLICT (forum_id, user_id) DO NOTHING
+	`
+	result, err := tx.ExecContext(ctx, query, forumID, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	// Find out why the ballot was not cast
+	if rowsAffected == 0 {
+		var closed bool
+		query = `
+			SELECT closes_at IS NOT NULL AND closes_at <= NOW()
+			FROM polls
+			WHERE forum_id = $1
+		`
+		err = tx.QueryRowContext(ctx, query, forumID).Scan(&closed)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		case err != nil:
+			return err
+		case closed:
+			return ErrPollClosed
+		default:
+			return ErrAlreadyVoted
+		}
+	}
+
+	query = `
+		INSERT INTO poll_votes (forum_id, user_id, option_id)
+		SELECT $1, $2, unnest($3::bigint[])
+	`
+	_, err = tx.ExecContext(ctx, query, forumID, userID, pq.Array(optionIDs))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,98 @@
+// Filename: internal/data/polls_test.go
+
+package data
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+// newTestPoll() returns a valid poll with the given options
+func newTestPoll(texts ...string) *Poll {
+	poll := &Poll{Question: "Which one?"}
+	for _, text := range texts {
+		poll.Options = append(poll.Options, &PollOption{Text: text})
+	}
+	return poll
+}
+
+func TestValidatePoll(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+	manyOptions := make([]string, 21)
+	for i := range manyOptions {
+		manyOptions[i] = strings.Repeat("x", i+1)
+	}
+	tests := []struct {
+		name   string
+		modify func(*Poll)
+		field  string
+	}{
+		{"valid", func(p *Poll) {}, ""},
+		{"closing in the future", func(p *Poll) { p.ClosesAt = &future }, ""},
+		{"hiding results until it closes", func(p *Poll) { p.HideResults, p.ClosesAt = true, &future }, ""},
+		{"no question", func(p *Poll) { p.Question = "  " }, "poll.question"},
+		{"long question", func(p *Poll) { p.Question = strings.Repeat("q", 501) }, "poll.question"},
+		{"one option", func(p *Poll) { p.Options = p.Options[:1] }, "poll.options"},
+		{"too many options", func(p *Poll) { *p = *newTestPoll(manyOptions...) }, "poll.options"},
+		{"empty option", func(p *Poll) { p.Options[1].Text = " " }, "poll.options"},
+		{"long option", func(p *Poll) { p.Options[1].Text = strings.Repeat("o", 201) }, "poll.options"},
+		{"duplicate options", func(p *Poll) { p.Options[1].Text = p.Options[0].Text }, "poll.options"},
+		{"closed already", func(p *Poll) { p.ClosesAt = &past }, "poll.closes_at"},
+		{"hiding results forever", func(p *Poll) { p.HideResults = true }, "poll.closes_at"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			poll := newTestPoll("yes", "no")
+			tt.modify(poll)
+			v := validator.New()
+			ValidatePoll(v, poll)
+			if tt.field == "" {
+				if !v.Valid() {
+					t.Errorf("got errors %v; want none", v.Errors)
+				}
+				return
+			}
+			if _, ok := v.Errors[tt.field]; !ok || len(v.Errors) != 1 {
+				t.Errorf("got errors %v; want one for %s", v.Errors, tt.field)
+			}
+		})
+	}
+}
+
+func TestValidateVote(t *testing.T) {
+	single := newTestPoll("yes", "no", "maybe")
+	multiple := newTestPoll("red", "green", "blue")
+	multiple.MultipleChoice = true
+	for i, option := range append(single.Options, multiple.Options...) {
+		option.ID = int64(i + 1)
+	}
+	tests := []struct {
+		name      string
+		poll      *Poll
+		optionIDs []int64
+		valid     bool
+	}{
+		{"single choice", single, []int64{2}, true},
+		{"several choices on a single choice poll", single, []int64{1, 2}, false},
+		{"no choice", single, nil, false},
+		{"option of another poll", single, []int64{4}, false},
+		{"unknown option", single, []int64{42}, false},
+		{"several choices", multiple, []int64{4, 6}, true},
+		{"every choice", multiple, []int64{4, 5, 6}, true},
+		{"repeated choice", multiple, []int64{4, 4}, false},
+		{"no choice on a multiple choice poll", multiple, []int64{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := validator.New()
+			ValidateVote(v, tt.poll, tt.optionIDs)
+			if v.Valid() != tt.valid {
+				t.Errorf("got valid %t; want %t (errors %v)", v.Valid(), tt.valid, v.Errors)
+			}
+		})
+	}
+}
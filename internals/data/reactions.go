@@ -0,0 +1,157 @@
+// Filename : internal/data/reactions.go
+
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// A Reaction is the number of users who reacted to a forum or a reply with
+// an emoji, and whether the current user is one of them
+type Reaction struct {
+	Emoji       string `json:"emoji"`
+	Count       int64  `json:"count"`
+	ReactedByMe bool   `json:"reacted_by_me"`
+}
+
+// A Reactor is a user who reacted with an emoji
+type Reactor struct {
+	UserSummary
+	ReactedAt time.Time `json:"reacted_at"`
+}
+
+// define a ReactionModel object that wraps a sql.DB connection pool
+type ReactionModel struct {
+	DB *sql.DB
+}
+
+// Insert() adds the reaction of a user to a forum or a reply (forumID or
+// replyID is 0). Reacting twice with the same emoji is a no-op
+func (m ReactionModel) Insert(forumID, replyID, userID int64, emoji string) error {
+	query := `
+		INSERT INTO reactions (forum_id, reply_id, user_id, emoji)
+		VALUES (NULLIF($1, 0), NULLIF($2, 0), $3, $4)
+		ON CONFLICT DO NOTHING
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, forumID, replyID, userID, emoji)
+	return err
+}
+
+// Delete() removes the reaction of a user from a forum or a reply. Removing
+// a reaction that does not exist is a no-op
+func (m ReactionModel) Delete(forumID, replyID, userID int64, emoji string) error {
+	query := `
+		DELETE FROM reactions
+		WHERE (forum_id = $1 OR reply_id = $2)
+		AND user_id = $3
+		AND emoji = $4
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, forumID, replyID, userID, emoji)
+	return err
+}
+
+// GetForForums() returns the reactions to each of the forums by forum id,
+// for a whole page of forums at once. Only the emojis given are counted, in
+// their order
+func (m ReactionModel) GetForForums(forumIDs []int64, userID int64, emojis []string) (map[int64][]*Reaction, error) {
+	return m.getFor("forum_id", forumIDs, userID, emojis)
+}
+
+// GetForReplies() returns the reactions to each of the replies by reply id
+func (m ReactionModel) GetForReplies(replyIDs []int64, userID int64, emojis []string) (map[int64][]*Reaction, error) {
+	return m.getFor("reply_id", replyIDs, userID, emojis)
+}
+
+// getFor() counts the reactions grouped by column, which is forum_id or
+// reply_id
+func (m ReactionModel) getFor(column string, ids []int64, userID int64, emojis []string) (map[int64][]*Reaction, error) {
+	reactions := make(map[int64][]*Reaction)
+	if len(ids) == 0 {
+		return reactions, nil
+	}
+	query := fmt.Sprintf(`
+		SELECT %[1]s, emoji, COUNT(*), bool_or(user_id = $2)
+		FROM reactions
+		WHERE %[1]s = ANY($1)
+		AND emoji = ANY($3)
+		GROUP BY %[1]s, emoji
+		ORDER BY %[1]s, array_position($3, emoji)`, column)
+
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids), userID, pq.Array(emojis))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var reaction Reaction
+		err := rows.Scan(&id, &reaction.Emoji, &reaction.Count, &reaction.ReactedByMe)
+		if err != nil {
+			return nil, err
+		}
+		reactions[id] = append(reactions[id], &reaction)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return reactions, nil
+}
+
+// GetReactors() returns a page of the users who reacted to a forum or a
+// reply with an emoji, the most recent first
+func (m ReactionModel) GetReactors(forumID, replyID int64, emoji string, filters Filters) ([]*Reactor, Metadata, error) {
+	query := `
+		SELECT COUNT(*) OVER(), users.id, users.name, reactions.created_at
+		FROM reactions
+		INNER JOIN users ON users.id = reactions.user_id
+		WHERE (reactions.forum_id = $1 OR reactions.reply_id = $2)
+		AND reactions.emoji = $3
+		ORDER BY reactions.id DESC
+		LIMIT $4 OFFSET $5
+	`
+	// Create a 3-second-timout context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, forumID, replyID, emoji, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	reactors := []*Reactor{}
+	for rows.Next() {
+		var reactor Reactor
+		err := rows.Scan(&totalRecords, &reactor.ID, &reactor.Name, &reactor.ReactedAt)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		reactors = append(reactors, &reactor)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return reactors, metadata, nil
+}
@@ -0,0 +1,62 @@
+// Filename : internal/data/reads.go
+
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// A ReadPosition is how far a user has read a forum
+type ReadPosition struct {
+	ForumID         int64     `json:"forum_id"`
+	LastReadReplyID int64     `json:"last_read_reply_id"`
+	UnreadCount     int64     `json:"unread_count"`
+	ReadAt          time.Time `json:"read_at"`
+}
+
+// define a ReadModel object that wraps a sql.DB connection pool
+type ReadModel struct {
+	DB *sql.DB
+}
+
+// MarkRead() records that a user has read a forum up to and including a
+// reply, or all of it when replyID is 0. Moving the position back marks the
+// later replies as unread again. The caller checks that the reply is part of
+// the forum
+func (m ReadModel) MarkRead(userID, forumID, replyID int64) (*ReadPosition, error) {
+	query := `
+		INSERT INTO forum_reads (user_id, forum_id, last_read_reply_id)
+		VALUES ($1, $2, CASE WHEN $3::bigint = 0
+		                     THEN COALESCE((SELECT MAX(id) FROM replies WHERE forums_id = $2), 0)
+		                     ELSE $3::bigint END)
+		ON CONFLICT (user_id, forum_id) DO UPDATE
+		SET last_read_reply_id = EXCLUDED.last_read_reply_id, read_at = NOW()
+		RETURNING last_read_reply_id, read_at
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	position := ReadPosition{ForumID: forumID}
+	err := m.DB.QueryRowContext(ctx, query, userID, forumID, replyID).Scan(&position.LastReadReplyID, &position.ReadAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// The replies of the user themselves are never unread
+	query = `
+		SELECT COUNT(*)
+		FROM replies
+		WHERE forums_id = $1
+		AND id > $2
+		AND users_id IS DISTINCT FROM $3
+	`
+	err = m.DB.QueryRowContext(ctx, query, forumID, position.LastReadReplyID, userID).Scan(&position.UnreadCount)
+	if err != nil {
+		return nil, err
+	}
+	return &position, nil
+}
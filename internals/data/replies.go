@@ -0,0 +1,512 @@
+// Filename : internal/data/replies.go
+
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"universityforum.miguelavila.net/internals/markdown"
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+type Reply struct {
+	ID        int64     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	// Message holds the markdown source and MessageHTML the sanitized HTML
+	// rendered from it whenever the reply is saved
+	Message     string `json:"message"`
+	MessageHTML string `json:"message_html,omitempty"`
+	// Mentions are the users mentioned in the message
+	Mentions Mentions `json:"mentions"`
+	// Mentioned holds the users mentioned for the first time by the last
+	// insert or update, who are due a notification
+	Mentioned []*User `json:"-"`
+	UserID    int64   `json:"user_id"`
+	ForumID   int64   `json:"forum_id"`
+	ParentID  int64   `json:"parent_id,omitempty"`
+	Version   int32   `json:"version"`
+	// Score is the sum of the up and down votes on the reply
+	Score int64 `json:"score"`
+	// Accepted is set on the accepted answer of a question forum
+	Accepted bool `json:"accepted"`
+	// The emoji reactions to the reply, when they were asked for
+	Reactions []*Reaction `json:"reactions,omitempty"`
+	// Only filled in when replies are retrieved as a tree
+	ReplyCount int      `json:"reply_count,omitempty"`
+	Replies    []*Reply `json:"replies,omitempty"`
+}
+
+// replyAccepted is a select expression telling whether a reply is the
+// accepted answer of its forum
+const replyAccepted = `COALESCE(replies.id = (SELECT accepted_reply_id FROM forums WHERE forums.id = replies.forums_id), false)`
+
+// define a ReplyModel object that wraps a sql.DB connection pool
+type ReplyModel struct {
+	DB *sql.DB
+}
+
+func ValidateReply(v *validator.Validator, reply *Reply) {
+	// Use the Check() method to execute our validation checks
+	v.Check(reply.Message != "", "message", "must be provided")
+	v.Check(len(reply.Message) <= 10000, "message", "must not be more than 10000 bytes long")
+}
+
+// Insert() allows us to create a new Reply along with its mentions. The
+// author is subscribed to the forum
+func (m ReplyModel) Insert(reply *Reply) error {
+	query := `
+		INSERT INTO replies (message, message_html, mentions, users_id, forums_id, parent_id)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0))
+		RETURNING id, created_at, version
+	`
+	// Render the message once so reads do not have to
+	html, err := markdown.Render(reply.Message)
+	if err != nil {
+		return err
+	}
+	reply.MessageHTML = html
+
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	// The reply and its mentions are written in a single transaction
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	mentions, users, err := resolveMentions(ctx, tx, reply.Message)
+	if err != nil {
+		return err
+	}
+	reply.Mentions = mentions
+
+	// Collect the data fields into a slice
+	args := []interface{}{
+		reply.Message, reply.MessageHTML, reply.Mentions, reply.UserID, reply.ForumID, reply.ParentID,
+	}
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&reply.ID, &reply.CreatedAt, &reply.Version)
+	if err != nil {
+		return err
+	}
+	reply.Mentioned, err = recordMentions(ctx, tx, 0, reply.ID, reply.UserID, users)
+	if err != nil {
+		return err
+	}
+	// Repliers follow the forum they replied to
+	err = autoSubscribe(ctx, tx, reply.ForumID, reply.UserID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetVisible() allows us to retrieve a specific Reply on behalf of a user.
+// Replies of forums in the trash are not found, and neither are the replies
+// of drafts and scheduled forums unless the user is the author of the forum
+func (m ReplyModel) GetVisible(id, userID int64) (*Reply, error) {
+	// Ensure that there is a valid id
+	if id < 1 {
+		return nil, ErrRecordNotFound
+	}
+	// Create the query
+	query := `
+		SELECT replies.id, replies.created_at, replies.message, replies.message_html, replies.mentions, replies.users_id,
+		       replies.forums_id, COALESCE(replies.parent_id, 0), replies.version, replies.score,
+		       COALESCE(forums.accepted_reply_id = replies.id, false)
+		FROM replies
+		INNER JOIN forums ON forums.id = replies.forums_id
+		WHERE replies.id = $1
+		AND forums.deleted_at IS NULL
+		AND (forums.status = 'published' OR forums.author_id = $2)
+	`
+	// Declare a Reply variable to hold the returned data
+	var reply Reply
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+	// Execute the query using QueryRow()
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(
+		&reply.ID,
+		&reply.CreatedAt,
+		&reply.Message,
+		&reply.MessageHTML,
+		&reply.Mentions,
+		&reply.UserID,
+		&reply.ForumID,
+		&reply.ParentID,
+		&reply.Version,
+		&reply.Score,
+		&reply.Accepted,
+	)
+	// Handle any errors
+	if err != nil {
+		// Check the type of error
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	// Success
+	return &reply, nil
+}
+
+// Update() allows us to edit/alter a specific Reply. The version being
+// replaced is kept in the revision history along with editorID, the user
+// making the change
+// Optimistic locking (version number)
+func (m ReplyModel) Update(reply *Reply, editorID int64) error {
+	// Create the query
+	query := `
+		UPDATE replies
+		SET message = $1, message_html = $2, mentions = $3, version = version + 1
+		WHERE id = $4
+		AND version = $5
+		RETURNING version
+	`
+	// Render the message once so reads do not have to
+	html, err := markdown.Render(reply.Message)
+	if err != nil {
+		return err
+	}
+	reply.MessageHTML = html
+
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	// The revision, the update and the mentions are written in a single
+	// transaction
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	mentions, users, err := resolveMentions(ctx, tx, reply.Message)
+	if err != nil {
+		return err
+	}
+	reply.Mentions = mentions
+
+	args := []interface{}{
+		reply.Message,
+		reply.MessageHTML,
+		reply.Mentions,
+		reply.ID,
+		reply.Version,
+	}
+
+	err = saveReplyRevision(ctx, tx, reply, editorID)
+	if err != nil {
+		return err
+	}
+	// Check for edit conflicts
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&reply.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+	// Only the users who were not mentioned before are due a notification
+	reply.Mentioned, err = recordMentions(ctx, tx, 0, reply.ID, reply.UserID, users)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete() removes a specific Reply
+func (m ReplyModel) Delete(id int64) error {
+	// Ensure that there is a valid id
+	if id < 1 {
+		return ErrRecordNotFound
+	}
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	// The nested replies go with the reply, and with them the votes their
+	// authors received, so their reputation is recomputed in the same
+	// transaction
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		WITH RECURSIVE subtree AS (
+			SELECT id, users_id FROM replies WHERE id = $1
+			UNION ALL
+			SELECT replies.id, replies.users_id FROM replies
+			INNER JOIN subtree ON replies.parent_id = subtree.id
+		)
+		SELECT ARRAY(SELECT DISTINCT users_id FROM subtree)
+	`
+	var authors []int64
+	err = tx.QueryRowContext(ctx, query, id).Scan(pq.Array(&authors))
+	if err != nil {
+		return err
+	}
+
+	// Create the delete query
+	query = `
+		DELETE FROM replies
+		WHERE id = $1
+	`
+	// Execute the query
+	result, err := tx.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	// Check how many rows were affected by the delete operation
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	// Check if no rows were affected
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+	err = recomputeReputation(ctx, tx, authors)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// The GetAllForForum() method returns a page of the replies posted to a
+// forum. The accepted answer always comes first. The listing can be
+// continued from a cursor
+func (m ReplyModel) GetAllForForum(forumID int64, filters Filters) ([]*Reply, Metadata, error) {
+	keys := replyKeyset(filters)
+	args := []interface{}{forumID, filters.limit() + 1, filters.offset()}
+	after, afterArgs := keys.after(filters.Cursor, len(args)+1)
+	args = append(args, afterArgs...)
+	// Construct the query. One more reply than the page holds is fetched to
+	// tell whether there is a next page
+	query := fmt.Sprintf(`
+		SELECT %s, id, created_at, message, message_html, mentions, users_id, forums_id,
+		       COALESCE(parent_id, 0), version, score, %s AS accepted, %s
+		FROM replies
+		WHERE forums_id = $1
+		AND %s
+		ORDER BY %s
+		LIMIT $2 OFFSET $3`, filters.total(), replyAccepted, keys.values(), after, keys.orderBy())
+
+	// Create a 3-second-timout context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	// Execute the query
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	// Close the resultset
+	defer rows.Close()
+	totalRecords := 0
+	// Initialize an empty slice to hold the Reply data
+	replies := []*Reply{}
+	// The sort key values of each reply, for the next cursor
+	positions := [][]string{}
+	// Iterate over the rows in the resultset
+	for rows.Next() {
+		var reply Reply
+		var position pq.StringArray
+		// Scan the values from the row into reply
+		err := rows.Scan(
+			&totalRecords,
+			&reply.ID,
+			&reply.CreatedAt,
+			&reply.Message,
+			&reply.MessageHTML,
+			&reply.Mentions,
+			&reply.UserID,
+			&reply.ForumID,
+			&reply.ParentID,
+			&reply.Version,
+			&reply.Score,
+			&reply.Accepted,
+			&position,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		// Add the Reply to our slice
+		replies = append(replies, &reply)
+		positions = append(positions, position)
+	}
+	// Check for errors after looping through the resultset
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	more := len(replies) > filters.PageSize
+	var last []string
+	if more {
+		replies = replies[:filters.PageSize]
+		last = positions[filters.PageSize-1]
+	}
+	metadata := calculateKeysetMetadata(totalRecords, more, last, filters)
+	// Return the slice of Replies
+	return replies, metadata, nil
+}
+
+// ValidateReplyFilters() checks the filters of a reply listing, including
+// its cursor
+func ValidateReplyFilters(v *validator.Validator, f Filters) {
+	if ValidateFilters(v, f); v.Valid() {
+		validateCursor(v, f, replyKeyset(f))
+	}
+}
+
+// replyKeyset() is the order of a reply listing. The accepted answer comes
+// first
+func replyKeyset(filters Filters) keyset {
+	column := "replies." + filters.sortColumn()
+	keys := keyset{{expr: replyAccepted, desc: true}, {expr: column, desc: filters.sortOrder() == "DESC"}}
+	// The id breaks the ties of the other columns
+	if column != "replies.id" {
+		keys = append(keys, sortKey{expr: "replies.id"})
+	}
+	return keys
+}
+
+// The GetTreeForForum() method returns a page of the replies directly under
+// parentID (0 for the top level replies of the forum) with their descendants
+// nested below them, down to maxDepth levels, with the first maxChildren
+// replies under each of them. The whole page is loaded with a single recursive
+// query. A reply whose ReplyCount is larger than the number of
+// nested Replies has more descendants than were loaded; they can be paged
+// through by passing its id as parentID. The accepted answer comes first
+// among the roots, which can be continued from a cursor
+func (m ReplyModel) GetTreeForForum(forumID, parentID int64, maxDepth, maxChildren int, filters Filters) ([]*Reply, Metadata, error) {
+	keys := replyKeyset(filters)
+	args := []interface{}{forumID, parentID, filters.limit(), filters.offset(), maxDepth, maxChildren}
+	after, afterArgs := keys.after(filters.Cursor, len(args)+1)
+	args = append(args, afterArgs...)
+	// Construct the query. The roots are paginated and each descendant
+	// carries the position of its root so the page order is kept. One more
+	// root than the page holds is fetched to tell whether there is a next
+	// page, but its descendants are not loaded. The children of each reply are
+	// limited with a lateral subquery since a recursive query cannot limit
+	// them itself
+	query := fmt.Sprintf(`
+		WITH RECURSIVE candidates AS (
+			SELECT id, %[1]s AS total, %[2]s AS keys,
+			       ROW_NUMBER() OVER(ORDER BY %[3]s) AS position
+			FROM replies
+			WHERE forums_id = $1
+			AND ((parent_id IS NULL AND $2 = 0) OR parent_id = $2)
+			AND %[4]s
+			ORDER BY %[3]s
+			LIMIT $3 + 1 OFFSET $4
+		), roots AS (
+			SELECT id, total, keys, position, (SELECT COUNT(*) FROM candidates) > $3 AS more
+			FROM candidates
+			ORDER BY position
+			LIMIT $3
+		), tree AS (
+			SELECT roots.id, roots.total, roots.position, 1 AS depth
+			FROM roots
+			UNION ALL
+			SELECT children.id, tree.total, tree.position, tree.depth + 1
+			FROM tree
+			CROSS JOIN LATERAL (
+				SELECT replies.id
+				FROM replies
+				WHERE replies.parent_id = tree.id
+				ORDER BY replies.id
+				LIMIT $6
+			) AS children
+			WHERE tree.depth < $5
+		)
+		SELECT tree.total, tree.depth, replies.id, replies.created_at,
+		       replies.message, replies.message_html, replies.mentions, replies.users_id, replies.forums_id,
+		       COALESCE(replies.parent_id, 0), replies.version, replies.score, %[5]s,
+		       (SELECT COUNT(*) FROM replies AS children WHERE children.parent_id = replies.id),
+		       roots.keys, COALESCE(roots.more, false)
+		FROM tree
+		INNER JOIN replies ON replies.id = tree.id
+		LEFT JOIN roots ON roots.id = tree.id AND tree.depth = 1
+		ORDER BY tree.position, tree.depth, replies.id`,
+		filters.total(), keys.values(), keys.orderBy(), after, replyAccepted)
+
+	// Create a 3-second-timout context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	// Execute the query
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	// Close the resultset
+	defer rows.Close()
+	totalRecords := 0
+	// The top level of the tree and an index used to attach the children.
+	// Rows come ordered by depth so a parent is always seen before its children
+	roots := []*Reply{}
+	index := make(map[int64]*Reply)
+	// The sort key values of the last root, for the next cursor
+	var last pq.StringArray
+	more := false
+	for rows.Next() {
+		var reply Reply
+		var depth int
+		var position pq.StringArray
+		var rootMore bool
+		err := rows.Scan(
+			&totalRecords,
+			&depth,
+			&reply.ID,
+			&reply.CreatedAt,
+			&reply.Message,
+			&reply.MessageHTML,
+			&reply.Mentions,
+			&reply.UserID,
+			&reply.ForumID,
+			&reply.ParentID,
+			&reply.Version,
+			&reply.Score,
+			&reply.Accepted,
+			&reply.ReplyCount,
+			&position,
+			&rootMore,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		index[reply.ID] = &reply
+		if depth == 1 {
+			roots = append(roots, &reply)
+			last, more = position, rootMore
+			continue
+		}
+		if parent, ok := index[reply.ParentID]; ok {
+			parent.Replies = append(parent.Replies, &reply)
+		}
+	}
+	// Check for errors after looping through the resultset
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	metadata := calculateKeysetMetadata(totalRecords, more, last, filters)
+	// Return the top level of the tree
+	return roots, metadata, nil
+}
@@ -0,0 +1,257 @@
+// Filename : internal/data/revisions.go
+
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// A ForumRevision is the content a forum had at a given version. EditedBy
+// and EditedAt tell who replaced that version and when; they are empty for
+// the current version
+type ForumRevision struct {
+	ForumID     int64        `json:"forum_id"`
+	Version     int32        `json:"version"`
+	Title       string       `json:"title"`
+	Description string       `json:"description"`
+	EditedBy    *UserSummary `json:"edited_by,omitempty"`
+	EditedAt    *time.Time   `json:"edited_at,omitempty"`
+}
+
+// A ReplyRevision is the message a reply had at a given version
+type ReplyRevision struct {
+	ReplyID  int64        `json:"reply_id"`
+	Version  int32        `json:"version"`
+	Message  string       `json:"message"`
+	EditedBy *UserSummary `json:"edited_by,omitempty"`
+	EditedAt *time.Time   `json:"edited_at,omitempty"`
+}
+
+// define a RevisionModel object that wraps a sql.DB connection pool
+type RevisionModel struct {
+	DB *sql.DB
+}
+
+// saveForumRevision() copies the current title and description of a forum
+// into its history before an update replaces them. It runs inside the
+// transaction of the update, so the copy is discarded on an edit conflict
+func saveForumRevision(ctx context.Context, tx *sql.Tx, forum *Forum, editorID int64) error {
+	query := `
+		INSERT INTO forum_revisions (forum_id, version, title, description, edited_by)
+		SELECT id, version, title, description, $3
+		FROM forums
+		WHERE id = $1 AND version = $2
+		ON CONFLICT (forum_id, version) DO NOTHING
+	`
+	_, err := tx.ExecContext(ctx, query, forum.ID, forum.Version, editorID)
+	return err
+}
+
+// saveReplyRevision() copies the current message of a reply into its
+// history before an update replaces it
+func saveReplyRevision(ctx context.Context, tx *sql.Tx, reply *Reply, editorID int64) error {
+	query := `
+		INSERT INTO reply_revisions (reply_id, version, message, edited_by)
+		SELECT id, version, message, $3
+		FROM replies
+		WHERE id = $1 AND version = $2
+		ON CONFLICT (reply_id, version) DO NOTHING
+	`
+	_, err := tx.ExecContext(ctx, query, reply.ID, reply.Version, editorID)
+	return err
+}
+
+// revisionEditor() builds the editor summary of a revision. The editor may
+// have been deleted since
+func revisionEditor(id int64, name string) *UserSummary {
+	if id == 0 {
+		return nil
+	}
+	return &UserSummary{ID: id, Name: name}
+}
+
+// GetForForum() allows us to retrieve a specific past version of a forum
+func (m RevisionModel) GetForForum(forumID int64, version int32) (*ForumRevision, error) {
+	query := `
+		SELECT forum_id, version, title, description, COALESCE(edited_by, 0),
+		       COALESCE((SELECT name FROM users WHERE users.id = forum_revisions.edited_by), ''),
+		       edited_at
+		FROM forum_revisions
+		WHERE forum_id = $1 AND version = $2
+	`
+	var revision ForumRevision
+	var editorID int64
+	var editorName string
+	var editedAt time.Time
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, forumID, version).Scan(
+		&revision.ForumID,
+		&revision.Version,
+		&revision.Title,
+		&revision.Description,
+		&editorID,
+		&editorName,
+		&editedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	revision.EditedBy = revisionEditor(editorID, editorName)
+	revision.EditedAt = &editedAt
+	return &revision, nil
+}
+
+// GetForReply() allows us to retrieve a specific past version of a reply
+func (m RevisionModel) GetForReply(replyID int64, version int32) (*ReplyRevision, error) {
+	query := `
+		SELECT reply_id, version, message, COALESCE(edited_by, 0),
+		       COALESCE((SELECT name FROM users WHERE users.id = reply_revisions.edited_by), ''),
+		       edited_at
+		FROM reply_revisions
+		WHERE reply_id = $1 AND version = $2
+	`
+	var revision ReplyRevision
+	var editorID int64
+	var editorName string
+	var editedAt time.Time
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, replyID, version).Scan(
+		&revision.ReplyID,
+		&revision.Version,
+		&revision.Message,
+		&editorID,
+		&editorName,
+		&editedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+	revision.EditedBy = revisionEditor(editorID, editorName)
+	revision.EditedAt = &editedAt
+	return &revision, nil
+}
+
+// The GetAllForForum() method returns a page of the past versions of a forum
+func (m RevisionModel) GetAllForForum(forumID int64, filters Filters) ([]*ForumRevision, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), forum_id, version, title, description, COALESCE(edited_by, 0),
+		       COALESCE((SELECT name FROM users WHERE users.id = forum_revisions.edited_by), ''),
+		       edited_at
+		FROM forum_revisions
+		WHERE forum_id = $1
+		ORDER BY %s %s
+		LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortOrder())
+
+	// Create a 3-second-timout context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, forumID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	revisions := []*ForumRevision{}
+	for rows.Next() {
+		var revision ForumRevision
+		var editorID int64
+		var editorName string
+		var editedAt time.Time
+		err := rows.Scan(
+			&totalRecords,
+			&revision.ForumID,
+			&revision.Version,
+			&revision.Title,
+			&revision.Description,
+			&editorID,
+			&editorName,
+			&editedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		revision.EditedBy = revisionEditor(editorID, editorName)
+		revision.EditedAt = &editedAt
+		revisions = append(revisions, &revision)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return revisions, metadata, nil
+}
+
+// The GetAllForReply() method returns a page of the past versions of a reply
+func (m RevisionModel) GetAllForReply(replyID int64, filters Filters) ([]*ReplyRevision, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), reply_id, version, message, COALESCE(edited_by, 0),
+		       COALESCE((SELECT name FROM users WHERE users.id = reply_revisions.edited_by), ''),
+		       edited_at
+		FROM reply_revisions
+		WHERE reply_id = $1
+		ORDER BY %s %s
+		LIMIT $2 OFFSET $3`, filters.sortColumn(), filters.sortOrder())
+
+	// Create a 3-second-timout context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, replyID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	revisions := []*ReplyRevision{}
+	for rows.Next() {
+		var revision ReplyRevision
+		var editorID int64
+		var editorName string
+		var editedAt time.Time
+		err := rows.Scan(
+			&totalRecords,
+			&revision.ReplyID,
+			&revision.Version,
+			&revision.Message,
+			&editorID,
+			&editorName,
+			&editedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		revision.EditedBy = revisionEditor(editorID, editorName)
+		revision.EditedAt = &editedAt
+		revisions = append(revisions, &revision)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return revisions, metadata, nil
+}
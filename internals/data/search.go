@@ -0,0 +1,193 @@
+// Filename : internal/data/search.go
+
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+// The kinds of search results
+const (
+	SearchTypeForum = "forum"
+	SearchTypeReply = "reply"
+	SearchTypeUser  = "user"
+)
+
+// SearchTypes lists every kind of search result
+var SearchTypes = []string{SearchTypeForum, SearchTypeReply, SearchTypeUser}
+
+// A SearchResult is a forum, a reply or a user matching a search. Title is
+// the title of the forum, or of the forum replied to, or the name of the
+// user. Link is the API path of the resource
+type SearchResult struct {
+	Type    string  `json:"type"`
+	ID      int64   `json:"id"`
+	Title   string  `json:"title"`
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+	Link    string  `json:"link"`
+}
+
+// define a SearchModel object that wraps a sql.DB connection pool
+type SearchModel struct {
+	DB *sql.DB
+}
+
+// searchHeadline() is a select expression with the parts of column matching
+// search_query. The text is HTML escaped so the <mark> tags around the
+// matches are the only markup
+func searchHeadline(column string) string {
+	return fmt.Sprintf(`ts_headline('simple',
+		replace(replace(replace(%s, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+		search_query, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MinWords=5, MaxWords=20')`, column)
+}
+
+// forumSnippet is the text of a forum its search headline is made from: the
+// description when it matches search_query, or else the title, or else the
+// best matching reply. The weights of the search document tell which part
+// matched
+const forumSnippet = `CASE
+	WHEN ts_filter(forums.search_vector, '{b}') @@ search_query THEN forums.description
+	WHEN ts_filter(forums.search_vector, '{a}') @@ search_query THEN forums.title
+	ELSE COALESCE((
+		SELECT replies.message
+		FROM replies
+		WHERE replies.forums_id = forums.id
+		AND replies.search_vector @@ search_query
+		ORDER BY ts_rank_cd(replies.search_vector, search_query) DESC, replies.id
+		LIMIT 1
+	), forums.description)
+	END`
+
+// searchMatches is the common table expressions of the search queries. It
+// matches $1 against the forums, the replies and the users that $2 may see:
+// forums in the trash, and drafts and scheduled forums of others, are left
+// out along with their replies, and so are users who are not activated
+const searchMatches = `
+	WITH search AS (
+		SELECT websearch_to_tsquery('simple', $1) AS search_query
+	), matches AS (
+		SELECT 'forum' AS type, forums.id, forums.title,
+		       ts_rank_cd(forums.search_vector, search_query) AS score
+		FROM forums, search
+		WHERE forums.search_vector @@ search_query
+		AND forums.deleted_at IS NULL
+		AND (forums.status = 'published' OR forums.author_id = $2)
+		UNION ALL
+		SELECT 'reply', replies.id, forums.title,
+		       ts_rank_cd(replies.search_vector, search_query)
+		FROM replies
+		INNER JOIN forums ON forums.id = replies.forums_id, search
+		WHERE replies.search_vector @@ search_query
+		AND forums.deleted_at IS NULL
+		AND (forums.status = 'published' OR forums.author_id = $2)
+		UNION ALL
+		SELECT 'user', users.id, users.name,
+		       ts_rank_cd(to_tsvector('simple', users.name), search_query)
+		FROM users, search
+		WHERE to_tsvector('simple', users.name) @@ search_query
+		AND users.activated
+	)`
+
+func ValidateSearch(v *validator.Validator, query string, types []string) {
+	v.Check(query != "", "q", "must be provided")
+	v.Check(len(query) <= 200, "q", "must not be more than 200 bytes long")
+	v.Check(len(types) > 0, "type", "must be provided")
+	for _, t := range types {
+		v.Check(validator.In(t, SearchTypes...), "type", "must be forum, reply or user")
+	}
+}
+
+// Search() returns a page of the forums, replies and users matching query,
+// the best match first. Only the kinds in types are listed, but the facets
+// of the metadata count the matches of every kind. userID is the user
+// searching
+func (m SearchModel) Search(query string, types []string, userID int64, filters Filters) ([]*SearchResult, Metadata, error) {
+	// The snippets are only worked out for the page of results
+	resultsQuery := searchMatches + fmt.Sprintf(`, page AS (
+		SELECT COUNT(*) OVER() AS total, type, id, title, score
+		FROM matches
+		WHERE type = ANY($3)
+		ORDER BY score DESC, type, id
+		LIMIT $4 OFFSET $5
+	)
+	SELECT page.total, page.type, page.id, page.title, page.score,
+	       CASE page.type
+	       WHEN 'forum' THEN (SELECT %s FROM forums WHERE forums.id = page.id)
+	       WHEN 'reply' THEN (SELECT %s FROM replies WHERE replies.id = page.id)
+	       ELSE %s
+	       END
+	FROM page, search
+	ORDER BY page.score DESC, page.type, page.id`,
+		searchHeadline(forumSnippet), searchHeadline("replies.message"), searchHeadline("page.title"))
+
+	facetsQuery := searchMatches + `
+	SELECT type, COUNT(*)
+	FROM matches
+	GROUP BY type`
+
+	// Create a 3-second-timout context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, resultsQuery, query, userID, pq.Array(types), filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	results := []*SearchResult{}
+	for rows.Next() {
+		var result SearchResult
+		err := rows.Scan(&totalRecords, &result.Type, &result.ID, &result.Title, &result.Score, &result.Snippet)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		switch result.Type {
+		case SearchTypeForum:
+			result.Link = fmt.Sprintf("/v1/forums/%d", result.ID)
+		case SearchTypeReply:
+			result.Link = fmt.Sprintf("/v1/replies/%d", result.ID)
+		case SearchTypeUser:
+			// Users have no page of their own, so link to their forums
+			result.Link = fmt.Sprintf("/v1/forums?author=%d", result.ID)
+		}
+		results = append(results, &result)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	facets := make(map[string]int)
+	for _, t := range SearchTypes {
+		facets[t] = 0
+	}
+	rows, err = m.DB.QueryContext(ctx, facetsQuery, query, userID)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t string
+		var count int
+		err := rows.Scan(&t, &count)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		facets[t] = count
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	metadata.Facets = facets
+	return results, metadata, nil
+}
@@ -0,0 +1,99 @@
+// Filename : internal/data/subscriptions.go
+
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// define a SubscriptionModel object that wraps a sql.DB connection pool
+type SubscriptionModel struct {
+	DB *sql.DB
+}
+
+// autoSubscribe() subscribes a user to a forum they posted to, unless they
+// unsubscribed from it before. It runs inside the transaction of the insert
+func autoSubscribe(ctx context.Context, tx *sql.Tx, forumID, userID int64) error {
+	query := `
+		INSERT INTO forum_subscriptions (forum_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (forum_id, user_id) DO NOTHING
+	`
+	_, err := tx.ExecContext(ctx, query, forumID, userID)
+	return err
+}
+
+// Set() subscribes a user to a forum or unsubscribes them. Both operations
+// are idempotent
+func (m SubscriptionModel) Set(userID, forumID int64, subscribed bool) error {
+	query := `
+		INSERT INTO forum_subscriptions (forum_id, user_id, subscribed)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (forum_id, user_id) DO UPDATE SET subscribed = EXCLUDED.subscribed
+	`
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, forumID, userID, subscribed)
+	return err
+}
+
+// Exists() reports whether a user is subscribed to a forum
+func (m SubscriptionModel) Exists(userID, forumID int64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM forum_subscriptions
+			WHERE forum_id = $1 AND user_id = $2 AND subscribed
+		)
+	`
+	var exists bool
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, forumID, userID).Scan(&exists)
+	return exists, err
+}
+
+// GetSubscribers() returns the activated users subscribed to a forum, leaving
+// out the user who caused the notification
+func (m SubscriptionModel) GetSubscribers(forumID, exceptUserID int64) ([]*User, error) {
+	query := `
+		SELECT users.id, users.name, users.email
+		FROM forum_subscriptions
+		INNER JOIN users ON users.id = forum_subscriptions.user_id
+		WHERE forum_subscriptions.forum_id = $1
+		AND forum_subscriptions.subscribed
+		AND users.activated
+		AND users.id <> $2
+		ORDER BY users.id
+	`
+	// Fanning out to a busy forum may return many rows
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, forumID, exceptUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*User{}
+	for rows.Next() {
+		var user User
+		err := rows.Scan(&user.ID, &user.Name, &user.Email)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, &user)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
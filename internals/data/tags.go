@@ -0,0 +1,118 @@
+// Filename : internal/data/tags.go
+
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+// A Tag and the number of forums it is attached to
+type Tag struct {
+	Name       string `json:"name"`
+	UsageCount int64  `json:"usage_count"`
+}
+
+// define a TagModel object that wraps a sql.DB connection pool
+type TagModel struct {
+	DB *sql.DB
+}
+
+// NormalizeTags() trims and lowercases tag names and drops empty and
+// repeated ones
+func NormalizeTags(tags []string) []string {
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// ValidateTags() checks tags that went through NormalizeTags(), which
+// already dropped the empty and duplicate ones
+func ValidateTags(v *validator.Validator, tags []string) {
+	v.Check(len(tags) <= 10, "tags", "must not contain more than 10 tags")
+	for _, tag := range tags {
+		v.Check(len(tag) <= 30, "tags", "must not contain tags more than 30 bytes long")
+	}
+}
+
+// setForumTags() replaces the tags of a forum, creating the tags that do not
+// exist yet. It runs inside the transaction of the forum insert/update
+func setForumTags(ctx context.Context, tx *sql.Tx, forumID int64, tags []string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM forums_tags WHERE forum_id = $1`, forumID)
+	if err != nil {
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	query := `
+		INSERT INTO tags (name)
+		SELECT unnest($1::text[])
+		ON CONFLICT (name) DO NOTHING
+	`
+	_, err = tx.ExecContext(ctx, query, pq.Array(tags))
+	if err != nil {
+		return err
+	}
+	query = `
+		INSERT INTO forums_tags (forum_id, tag_id)
+		SELECT $1, tags.id FROM tags WHERE tags.name = ANY($2)
+	`
+	_, err = tx.ExecContext(ctx, query, forumID, pq.Array(tags))
+	return err
+}
+
+// The GetAll() method returns the tags that are in use together with the
+// number of published forums using each of them
+func (m TagModel) GetAll(filters Filters) ([]*Tag, Metadata, error) {
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) OVER(), tags.name, COUNT(forums_tags.forum_id) AS usage_count
+		FROM tags
+		INNER JOIN forums_tags ON forums_tags.tag_id = tags.id
+		INNER JOIN forums ON forums.id = forums_tags.forum_id
+		WHERE forums.deleted_at IS NULL
+		AND forums.status = 'published'
+		GROUP BY tags.id, tags.name
+		ORDER BY %s %s, name ASC
+		LIMIT $1 OFFSET $2`, filters.sortColumn(), filters.sortOrder())
+
+	// Create a 3-second-timout context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	tags := []*Tag{}
+	for rows.Next() {
+		var tag Tag
+		err := rows.Scan(&totalRecords, &tag.Name, &tag.UsageCount)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		tags = append(tags, &tag)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+	metadata := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return tags, metadata, nil
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"errors"
+	"regexp"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -14,8 +15,17 @@ import (
 var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrDuplicateEmail     = errors.New("duplicate email address")
+	ErrDuplicateUsername  = errors.New("duplicate username")
 )
 
+// UsernameRX matches the usernames users are mentioned by as "@username". A
+// username cannot end with a dot, which would end the sentence instead
+var UsernameRX = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9._-]{0,62}[A-Za-z0-9_-])?$`)
+
+// generatedUsernameRX matches the usernames given to users who do not choose
+// one, "user" followed by their id. Nobody may choose such a username
+var generatedUsernameRX = regexp.MustCompile(`(?i)^user[0-9]+$`)
+
 // Declare an AnonymousUser, no id, no name, no password, no email
 var AnonymousUser = &User{}
 
@@ -23,10 +33,20 @@ type User struct {
 	ID        int64     `json:"id"`
 	CreatedAt time.Time `json:"create_at"`
 	Name      string    `json:"name"`
+	Username  string    `json:"username"`
 	Email     string    `json:"email"`
 	Password  password  `json:"-"`
 	Activated bool      `json:"activated"`
-	Version   int64     `json:"-"`
+	// Reputation is derived from the votes and accepted answers the user
+	// received, see RecomputeReputation()
+	Reputation int64 `json:"reputation"`
+	Version    int64 `json:"-"`
+}
+
+// UserSummary is the public view of a user that is embedded in other resources
+type UserSummary struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
 }
 
 // check if a user is anonymous
@@ -86,6 +106,12 @@ func ValidateUser(v *validator.Validator, user *User) {
 	v.Check(user.Name != "", "name", "must be provided")
 	v.Check(len(user.Name) <= 500, "name", "must not be more than 500 bytes long")
 
+	// The username is optional, one is made up from the id otherwise
+	if user.Username != "" {
+		v.Check(validator.Matches(user.Username, UsernameRX), "username", "must be letters, digits, dots, dashes or underscores, at most 64 of them")
+		v.Check(!validator.Matches(user.Username, generatedUsernameRX), "username", "must not be user followed by a number")
+	}
+
 	// validate email
 	ValidateEmail(v, user.Email)
 	// validate password
@@ -105,13 +131,13 @@ type UserModel struct {
 	DB *sql.DB
 }
 
-// create a new user
+// create a new user. Users without a username are given one by the database
 func (m *UserModel) Insert(user *User) error {
 	// query database
 	query := `
-		INSERT INTO users (name, email, password_hash, activated)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, create_at, version
+		INSERT INTO users (name, email, password_hash, activated, username)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		RETURNING id, create_at, username, version
 	`
 
 	args := []interface{}{
@@ -119,17 +145,20 @@ func (m *UserModel) Insert(user *User) error {
 		user.Email,
 		user.Password.hash,
 		user.Activated,
+		user.Username,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.CreatedAt, &user.Version)
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID, &user.CreatedAt, &user.Username, &user.Version)
 
 	if err != nil {
 		switch {
 		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
 			return ErrDuplicateEmail
+		case err.Error() == `pq: duplicate key value violates unique constraint "users_username_key"`:
+			return ErrDuplicateUsername
 		default:
 			return err
 		}
@@ -140,7 +169,7 @@ func (m *UserModel) Insert(user *User) error {
 // Get user based on their email
 func (m UserModel) GetByEmail(email string) (*User, error) {
 	query := `
-		SELECT id, create_at, name, email, password_hash, version
+		SELECT id, create_at, name, username, email, password_hash, reputation, version
 		FROM users
 		WHERE email = $1
 	`
@@ -152,8 +181,10 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 		&user.ID,
 		&user.CreatedAt,
 		&user.Name,
+		&user.Username,
 		&user.Email,
 		&user.Password.hash,
+		&user.Reputation,
 		&user.Version,
 	)
 
@@ -202,12 +233,37 @@ func (m UserModel) Update(user *User) error {
 
 }
 
+// RecomputeReputation() rebuilds the reputation of the given users from the
+// votes and accepted answers they received, or of all users when none are
+// given
+func (m UserModel) RecomputeReputation(userIDs ...int64) error {
+	// Going over every user may take a while
+	timeout := 3 * time.Second
+	if len(userIDs) == 0 {
+		timeout = 5 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = recomputeReputation(ctx, tx, userIDs)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error) {
 	tokenHash := sha256.Sum256([]byte(tokenPlaintext))
 	// setup query
 	query := `
-		SELECT users.id, users.create_at, users.name, users.email, users.password_hash, 
-		users.activated, users.version
+		SELECT users.id, users.create_at, users.name, users.username, users.email, users.password_hash, 
+		users.activated, users.reputation, users.version
 		FROM users
 		INNER JOIN tokens on users.id = tokens.user_id
 		WHERE tokens.hash = $1
@@ -223,9 +279,11 @@ func (m UserModel) GetForToken(tokenScope, tokenPlaintext string) (*User, error)
 		&user.ID,
 		&user.CreatedAt,
 		&user.Name,
+		&user.Username,
 		&user.Email,
 		&user.Password.hash,
 		&user.Activated,
+		&user.Reputation,
 		&user.Version,
 	)
 
@@ -0,0 +1,45 @@
+// Filename: internal/data/users_test.go
+
+package data
+
+import (
+	"testing"
+
+	"universityforum.miguelavila.net/internals/validator"
+)
+
+func TestValidateUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		valid    bool
+	}{
+		{"", true},
+		{"alice", true},
+		{"a", true},
+		{"alice.b", true},
+		{"alice_", true},
+		{"alice.", false},
+		{".alice", false},
+		{"al ice", false},
+		{"user12", false},
+		{"User12", false},
+		{"user", true},
+		{"user12a", true},
+	}
+	// Hashing the password is slow, so it is done once
+	var hash password
+	err := hash.Set("pa55word")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			user := &User{Name: "Alice", Email: "alice@example.com", Username: tt.username, Password: hash}
+			v := validator.New()
+			ValidateUser(v, user)
+			if _, invalid := v.Errors["username"]; invalid == tt.valid {
+				t.Errorf("got errors %v; want valid %t", v.Errors, tt.valid)
+			}
+		})
+	}
+}
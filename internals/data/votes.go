@@ -0,0 +1,172 @@
+// Filename : internal/data/votes.go
+
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// The reputation a user earns from what others do with their replies
+const (
+	ReputationUpvote   = 10
+	ReputationDownvote = -2
+	ReputationAccepted = 15
+)
+
+// reputationExpr computes the reputation of the user in the users row from
+// scratch. Replies of forums in the trash still count until they are purged
+const reputationExpr = `
+	COALESCE((
+		SELECT SUM(CASE WHEN reply_votes.value > 0 THEN $1::integer ELSE $2::integer END)
+		FROM reply_votes
+		INNER JOIN replies ON replies.id = reply_votes.reply_id
+		WHERE replies.users_id = users.id
+	), 0) + $3::integer * (
+		SELECT COUNT(*)
+		FROM forums
+		INNER JOIN replies ON replies.id = forums.accepted_reply_id
+		WHERE replies.users_id = users.id
+	)`
+
+// recomputeReputation() rebuilds the reputation of the given users, or of all
+// users when none are given. The users are locked first so that the sums are
+// taken after the concurrent votes on their replies are committed; a single
+// UPDATE would sum them as they were when it started
+func recomputeReputation(ctx context.Context, tx *sql.Tx, userIDs []int64) error {
+	query := `
+		SELECT id FROM users
+		WHERE cardinality($1::bigint[]) = 0 OR id = ANY($1)
+		ORDER BY id
+		FOR UPDATE
+	`
+	_, err := tx.ExecContext(ctx, query, pq.Array(userIDs))
+	if err != nil {
+		return err
+	}
+	query = `
+		UPDATE users
+		SET reputation = ` + reputationExpr + `
+		WHERE cardinality($4::bigint[]) = 0 OR users.id = ANY($4)
+	`
+	args := []interface{}{
+		ReputationUpvote, ReputationDownvote, ReputationAccepted, pq.Array(userIDs),
+	}
+	_, err = tx.ExecContext(ctx, query, args...)
+	return err
+}
+
+// define a VoteModel object that wraps a sql.DB connection pool
+type VoteModel struct {
+	DB *sql.DB
+}
+
+// Set() records the vote of a user on a reply; a value of 0 withdraws it.
+// The score of the reply and the reputation of its author are brought up to
+// date in the same transaction, and the new score is returned
+func (m VoteModel) Set(replyID, userID int64, value int) (int64, error) {
+	// Create a context
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	// Cleanup to prevent memory leaks
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Votes on the reply are counted one at a time, so the score below sums
+	// the votes committed by the others
+	query := `
+		SELECT id FROM replies
+		WHERE id = $1
+		FOR UPDATE
+	`
+	err = tx.QueryRowContext(ctx, query, replyID).Scan(&replyID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	if value == 0 {
+		query := `
+			DELETE FROM reply_votes
+			WHERE reply_id = $1 AND user_id = $2
+		`
+		_, err = tx.ExecContext(ctx, query, replyID, userID)
+	} else {
+		query := `
+			INSERT INTO reply_votes (reply_id, user_id, value)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (reply_id, user_id) DO UPDATE SET value = EXCLUDED.value
+		`
+		_, err = tx.ExecContext(ctx, query, replyID, userID, value)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	query = `
+		UPDATE replies
+		SET score = (SELECT COALESCE(SUM(value), 0) FROM reply_votes WHERE reply_id = $1)
+		WHERE id = $1
+		RETURNING score, users_id
+	`
+	var score, authorID int64
+	err = tx.QueryRowContext(ctx, query, replyID).Scan(&score, &authorID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+	err = recomputeReputation(ctx, tx, []int64{authorID})
+	if err != nil {
+		return 0, err
+	}
+	return score, tx.Commit()
+}
+
+// RecomputeScores() rebuilds the score of every reply from its votes
+func (m VoteModel) RecomputeScores() error {
+	// Going over every reply may take a while
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Locked like Set() does, so votes cast meanwhile are not lost
+	query := `
+		SELECT id FROM replies
+		ORDER BY id
+		FOR UPDATE
+	`
+	_, err = tx.ExecContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	query = `
+		UPDATE replies
+		SET score = COALESCE((SELECT SUM(value) FROM reply_votes WHERE reply_votes.reply_id = replies.id), 0)
+	`
+	_, err = tx.ExecContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,107 @@
+// Filename: internal/diff/diff.go
+
+package diff
+
+import "strings"
+
+// The operations a line of a diff can carry
+const (
+	OpEqual  = "equal"
+	OpInsert = "insert"
+	OpDelete = "delete"
+)
+
+// A Line of a diff. Deleted lines come from the old text, inserted lines
+// from the new one
+type Line struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// maxCells bounds the size of the table Lines() works the longest common
+// subsequence out in, about 2MB. Texts that differ in more lines than it
+// allows are diffed as a whole block replaced by another
+const maxCells = 1 << 18
+
+// Lines() returns the line by line differences between the old and the new
+// text. It is based on the longest common subsequence of the lines that
+// differ, once the lines both texts start and end with are set aside
+func Lines(old, new string) []Line {
+	a := strings.Split(old, "\n")
+	b := strings.Split(new, "\n")
+
+	// The common prefix and suffix are equal lines as they are
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	lines := []Line{}
+	for _, text := range a[:prefix] {
+		lines = append(lines, Line{Op: OpEqual, Text: text})
+	}
+	lines = append(lines, middle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, Line{Op: OpEqual, Text: text})
+	}
+	return lines
+}
+
+// middle() diffs the lines between the common prefix and suffix
+func middle(a, b []string) []Line {
+	lines := []Line{}
+	if (len(a)+1)*(len(b)+1) > maxCells {
+		for _, text := range a {
+			lines = append(lines, Line{Op: OpDelete, Text: text})
+		}
+		for _, text := range b {
+			lines = append(lines, Line{Op: OpInsert, Text: text})
+		}
+		return lines
+	}
+
+	// lcs[i][j] holds the length of the longest common subsequence of
+	// a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	// Walk the table to build the diff
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, Line{Op: OpEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, Line{Op: OpDelete, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, Line{Op: OpInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, Line{Op: OpDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, Line{Op: OpInsert, Text: b[j]})
+	}
+	return lines
+}
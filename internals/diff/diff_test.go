@@ -0,0 +1,130 @@
+// Filename: internal/diff/diff_test.go
+
+package diff
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// sides() rebuilds the old and the new text from a diff
+func sides(lines []Line) (string, string) {
+	var a, b []string
+	for _, line := range lines {
+		if line.Op != OpInsert {
+			a = append(a, line.Text)
+		}
+		if line.Op != OpDelete {
+			b = append(b, line.Text)
+		}
+	}
+	return strings.Join(a, "\n"), strings.Join(b, "\n")
+}
+
+// numbered() returns n lines starting with prefix
+func numbered(prefix string, n int) string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%s %d", prefix, i)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want []Line
+	}{
+		{
+			name: "equal",
+			old:  "a\nb",
+			new:  "a\nb",
+			want: []Line{{OpEqual, "a"}, {OpEqual, "b"}},
+		},
+		{
+			name: "insert",
+			old:  "a\nc",
+			new:  "a\nb\nc",
+			want: []Line{{OpEqual, "a"}, {OpInsert, "b"}, {OpEqual, "c"}},
+		},
+		{
+			name: "delete",
+			old:  "a\nb\nc",
+			new:  "a\nc",
+			want: []Line{{OpEqual, "a"}, {OpDelete, "b"}, {OpEqual, "c"}},
+		},
+		{
+			name: "replace",
+			old:  "a\nb\nc",
+			new:  "a\nx\nc",
+			want: []Line{{OpEqual, "a"}, {OpDelete, "b"}, {OpInsert, "x"}, {OpEqual, "c"}},
+		},
+		{
+			name: "common middle",
+			old:  "x\nb\ny",
+			new:  "z\nb\nw",
+			want: []Line{{OpDelete, "x"}, {OpInsert, "z"}, {OpEqual, "b"}, {OpDelete, "y"}, {OpInsert, "w"}},
+		},
+		{
+			name: "repeated lines",
+			old:  "a\na",
+			new:  "a\na\na",
+			want: []Line{{OpEqual, "a"}, {OpEqual, "a"}, {OpInsert, "a"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Lines(tt.old, tt.new)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("got %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// The largest descriptions and messages are 10000 bytes, which is up to
+// 10000 lines. Diffing two of them must not build a table of every pair of
+// lines
+func TestLinesBound(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+	}{
+		{"all lines differ", strings.Repeat("\n", 9999), strings.Repeat("x\n", 5000)},
+		{"numbered lines differ", numbered("old", 5000), numbered("new", 5000)},
+		{"shared prefix and suffix", "start\n" + numbered("old", 5000) + "\nend", "start\n" + numbered("new", 5000) + "\nend"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			lines := Lines(tt.old, tt.new)
+			runtime.ReadMemStats(&after)
+
+			if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 16<<20 {
+				t.Errorf("allocated %d bytes; want at most %d", allocated, 16<<20)
+			}
+			old, new := sides(lines)
+			if old != tt.old || new != tt.new {
+				t.Error("the diff does not rebuild both texts")
+			}
+		})
+	}
+}
+
+func TestLinesKeepsPrefixAndSuffix(t *testing.T) {
+	old := "start\n" + numbered("old", 1000) + "\nend"
+	new := "start\n" + numbered("new", 1000) + "\nend"
+	lines := Lines(old, new)
+	if first := lines[0]; first != (Line{OpEqual, "start"}) {
+		t.Errorf("first line is %v; want the common prefix", first)
+	}
+	if last := lines[len(lines)-1]; last != (Line{OpEqual, "end"}) {
+		t.Errorf("last line is %v; want the common suffix", last)
+	}
+}
@@ -0,0 +1,137 @@
+// Filename: internal/events/broker.go
+
+package events
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// An Event is published to every subscriber of a topic. The data is encoded
+// once when the event is published
+type Event struct {
+	ID   int64
+	Type string
+	Data []byte
+}
+
+// A Subscription receives the events of a topic on C. C is closed when the
+// subscription ends, either because the broker was closed or because the
+// subscriber fell too far behind
+type Subscription struct {
+	C      <-chan Event
+	ch     chan Event
+	topic  string
+	broker *Broker
+	lagged bool
+}
+
+// Broker fans events out to the subscribers of a topic in the same process.
+// Publishing never blocks: a subscriber whose buffer is full is dropped and
+// has to reconnect. The broker is safe for concurrent use
+type Broker struct {
+	mu         sync.Mutex
+	topics     map[string]map[*Subscription]struct{}
+	lastID     int64
+	bufferSize int
+	closed     bool
+}
+
+// NewBroker() creates a broker that buffers up to bufferSize events for each
+// subscriber
+func NewBroker(bufferSize int) *Broker {
+	return &Broker{
+		topics:     make(map[string]map[*Subscription]struct{}),
+		bufferSize: bufferSize,
+	}
+}
+
+// Subscribe() starts receiving the events published to topic. The
+// subscription must be closed when it is no longer needed
+func (b *Broker) Subscribe(topic string) *Subscription {
+	ch := make(chan Event, b.bufferSize)
+	sub := &Subscription{C: ch, ch: ch, topic: topic, broker: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// A closed broker hands out subscriptions that are already over
+	if b.closed {
+		close(ch)
+		return sub
+	}
+	if b.topics[topic] == nil {
+		b.topics[topic] = make(map[*Subscription]struct{})
+	}
+	b.topics[topic][sub] = struct{}{}
+	return sub
+}
+
+// Publish() encodes data as JSON and sends it to the subscribers of topic
+func (b *Broker) Publish(topic, eventType string, data interface{}) error {
+	js, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.lastID++
+	event := Event{ID: b.lastID, Type: eventType, Data: js}
+	for sub := range b.topics[topic] {
+		select {
+		case sub.ch <- event:
+		default:
+			// The subscriber is not keeping up, so drop it rather than
+			// holding up everyone else
+			sub.lagged = true
+			b.remove(sub)
+		}
+	}
+	return nil
+}
+
+// Close() ends every subscription. Nothing is published after the broker
+// is closed
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for _, subs := range b.topics {
+		for sub := range subs {
+			b.remove(sub)
+		}
+	}
+}
+
+// remove() ends a subscription. The caller must hold the lock
+func (b *Broker) remove(sub *Subscription) {
+	subs, ok := b.topics[sub.topic]
+	if !ok {
+		return
+	}
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(b.topics, sub.topic)
+	}
+	close(sub.ch)
+}
+
+// Close() stops receiving events. It is safe to call more than once
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.remove(s)
+}
+
+// Lagged() reports whether the subscription was dropped for falling behind
+func (s *Subscription) Lagged() bool {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	return s.lagged
+}
@@ -0,0 +1,145 @@
+// Filename: internal/events/broker_test.go
+
+package events
+
+import (
+	"sync"
+	"testing"
+)
+
+// drain() returns the events left on a subscription, which must be over
+func drain(t *testing.T, sub *Subscription) []Event {
+	t.Helper()
+	var events []Event
+	for event := range sub.C {
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestPublish(t *testing.T) {
+	b := NewBroker(4)
+	defer b.Close()
+	first := b.Subscribe("forum:1")
+	second := b.Subscribe("forum:1")
+	other := b.Subscribe("forum:2")
+
+	err := b.Publish("forum:1", "reply.created", map[string]int{"id": 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sub := range []*Subscription{first, second} {
+		event := <-sub.C
+		if event.ID != 1 || event.Type != "reply.created" || string(event.Data) != `{"id":7}` {
+			t.Errorf("got %+v; want the published event", event)
+		}
+	}
+	select {
+	case event := <-other.C:
+		t.Errorf("got %+v on another topic", event)
+	default:
+	}
+}
+
+func TestPublishUnencodable(t *testing.T) {
+	b := NewBroker(4)
+	defer b.Close()
+	if err := b.Publish("forum:1", "bad", make(chan int)); err == nil {
+		t.Error("got no error for data that cannot be encoded as JSON")
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	b := NewBroker(2)
+	defer b.Close()
+	slow := b.Subscribe("forum:1")
+	fast := b.Subscribe("forum:1")
+
+	for i := 0; i < 3; i++ {
+		b.Publish("forum:1", "reply.created", i)
+		// The fast subscriber keeps up
+		<-fast.C
+	}
+	if events := drain(t, slow); len(events) != 2 {
+		t.Errorf("got %d buffered events; want 2", len(events))
+	}
+	if !slow.Lagged() {
+		t.Error("the slow subscriber is not marked as lagged")
+	}
+	if fast.Lagged() {
+		t.Error("the fast subscriber is marked as lagged")
+	}
+
+	// Only the fast subscriber is left
+	b.Publish("forum:1", "reply.created", 3)
+	if event := <-fast.C; event.ID != 4 {
+		t.Errorf("got event %d; want 4", event.ID)
+	}
+}
+
+func TestClose(t *testing.T) {
+	b := NewBroker(4)
+	sub := b.Subscribe("forum:1")
+	b.Publish("forum:1", "reply.created", 1)
+	b.Close()
+
+	// Events published before Close are still delivered
+	if events := drain(t, sub); len(events) != 1 {
+		t.Errorf("got %d events; want 1", len(events))
+	}
+	if sub.Lagged() {
+		t.Error("a subscription ended by Close is marked as lagged")
+	}
+	// Publishing after Close is a no-op
+	if err := b.Publish("forum:1", "reply.created", 2); err != nil {
+		t.Errorf("got %v publishing after Close", err)
+	}
+	// Late subscriptions are already over
+	if events := drain(t, b.Subscribe("forum:1")); len(events) != 0 {
+		t.Errorf("got %d events after Close; want none", len(events))
+	}
+	// Closing again is harmless
+	b.Close()
+	sub.Close()
+}
+
+func TestSubscriptionClose(t *testing.T) {
+	b := NewBroker(4)
+	defer b.Close()
+	sub := b.Subscribe("forum:1")
+	sub.Close()
+	sub.Close()
+	b.Publish("forum:1", "reply.created", 1)
+	if events := drain(t, sub); len(events) != 0 {
+		t.Errorf("got %d events after Close; want none", len(events))
+	}
+}
+
+// Run with -race: subscriptions closing while events are published must not
+// send on a closed channel or race on the topics
+func TestCloseRacesPublish(t *testing.T) {
+	b := NewBroker(1)
+	defer b.Close()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				b.Publish("forum:1", "reply.created", j)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				sub := b.Subscribe("forum:1")
+				select {
+				case <-sub.C:
+				default:
+				}
+				sub.Close()
+			}
+		}()
+	}
+	wg.Wait()
+}
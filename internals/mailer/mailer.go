@@ -32,46 +32,77 @@ func New(host string, port int, username, password, sender string) Mailer {
 	}
 }
 
+// A Message is one mail of a batch: who it goes to and the data its
+// template is executed with
+type Message struct {
+	Recipient string
+	Data      interface{}
+}
+
 // Send a mail
 func (m Mailer) Send(recipient, templateFile string, data interface{}) error {
+	return m.SendBatch(templateFile, []Message{{Recipient: recipient, Data: data}})
+}
+
+// SendBatch() sends a mail built from the same template to each recipient
+// over a single SMTP connection
+func (m Mailer) SendBatch(templateFile string, messages []Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
 	tmpl, err := template.New("email").ParseFS(templateFS, "templates/"+templateFile)
 	if err != nil {
 		return err
 	}
+
+	msgs := make([]*mail.Message, 0, len(messages))
+	for _, message := range messages {
+		msg, err := m.build(tmpl, message)
+		if err != nil {
+			return err
+		}
+		msgs = append(msgs, msg)
+	}
+
+	// Call DialAndSend
+	err = m.dailer.DialAndSend(msgs...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// build() executes the template for a single message
+func (m Mailer) build(tmpl *template.Template, message Message) (*mail.Message, error) {
 	// execute the template
 	subject := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(subject, "subject", data)
+	err := tmpl.ExecuteTemplate(subject, "subject", message.Data)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// execute the template
 	plainBody := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(plainBody, "plainBody", data)
+	err = tmpl.ExecuteTemplate(plainBody, "plainBody", message.Data)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// execute the template
 	htmlBody := new(bytes.Buffer)
-	err = tmpl.ExecuteTemplate(htmlBody, "htmlBody", data)
+	err = tmpl.ExecuteTemplate(htmlBody, "htmlBody", message.Data)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// create a new mail mssg
 	msg := mail.NewMessage()
-	msg.SetHeader("To", recipient)
+	msg.SetHeader("To", message.Recipient)
 	msg.SetHeader("From", m.sender)
 	msg.SetHeader("Subject", subject.String())
 	msg.SetBody("text/plain", plainBody.String())
 	msg.AddAlternative("text/html", htmlBody.String())
 
-	// Call DialAndSend
-	err = m.dailer.DialAndSend(msg)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return msg, nil
 }
@@ -0,0 +1,33 @@
+// Filename: internal/markdown/markdown.go
+
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+)
+
+// The CommonMark renderer. Raw HTML in the source is not rendered
+var renderer = goldmark.New()
+
+// The allowlist the rendered HTML is sanitized against. It is the policy for
+// user generated content, which also adds rel="nofollow" to links, plus the
+// language classes that fenced code blocks carry
+var policy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	return p
+}()
+
+// Render() converts CommonMark source to HTML that is safe to embed in a page
+func Render(source string) (string, error) {
+	var buf bytes.Buffer
+	err := renderer.Convert([]byte(source), &buf)
+	if err != nil {
+		return "", err
+	}
+	return policy.Sanitize(buf.String()), nil
+}
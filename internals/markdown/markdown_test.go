@@ -0,0 +1,109 @@
+// Filename: internal/markdown/markdown_test.go
+
+package markdown
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// unsafe lists what must never reach the rendered HTML. A javascript: URL
+// may still show up as text
+var unsafe = []string{"<script", "onerror", "onclick", "onload", "<iframe", "<style"}
+
+var javascriptURLRX = regexp.MustCompile(`(?i)(href|src)\s*=\s*["']?\s*javascript:`)
+
+func checkSafe(t *testing.T, html string) {
+	t.Helper()
+	lower := strings.ToLower(html)
+	for _, s := range unsafe {
+		if strings.Contains(lower, s) {
+			t.Errorf("%q contains %q", html, s)
+		}
+	}
+	if javascriptURLRX.MatchString(html) {
+		t.Errorf("%q links to a javascript: URL", html)
+	}
+}
+
+func TestRenderStripsUnsafeContent(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"script block", "<script>alert(1)</script>"},
+		{"inline script", "hello <script>alert(1)</script> world"},
+		{"javascript link", "[click](javascript:alert(1))"},
+		{"javascript link in caps", "[click](JaVaScRiPt:alert(1))"},
+		{"javascript autolink", "<javascript:alert(1)>"},
+		{"javascript image", "![x](javascript:alert(1))"},
+		{"event handler", `<img src="x.png" onerror="alert(1)">`},
+		{"event handler in a link", `<a href="https://example.com" onclick="alert(1)">x</a>`},
+		{"body onload", `<body onload="alert(1)">`},
+		{"iframe", `<iframe src="https://example.com"></iframe>`},
+		{"style", `<style>body { display: none }</style>`},
+		{"reference link", "[click][x]\n\n[x]: javascript:alert(1)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, err := Render(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkSafe(t, html)
+		})
+	}
+}
+
+// The policy is what keeps the HTML safe should the renderer ever let raw
+// HTML through
+func TestPolicyStripsUnsafeHTML(t *testing.T) {
+	tests := []string{
+		`<script>alert(1)</script>`,
+		`<a href="javascript:alert(1)">x</a>`,
+		`<a href="https://example.com" onclick="alert(1)">x</a>`,
+		`<img src="x.png" onerror="alert(1)">`,
+		`<p onmouseover="alert(1)">x</p>`,
+		`<iframe src="https://example.com"></iframe>`,
+		`<code class="language-go" onclick="alert(1)">x</code>`,
+	}
+	for _, html := range tests {
+		sanitized := policy.Sanitize(html)
+		checkSafe(t, sanitized)
+		if strings.Contains(sanitized, "onmouseover") {
+			t.Errorf("%q contains an event handler", sanitized)
+		}
+	}
+}
+
+func TestRenderKeepsMarkdown(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"emphasis", "*hi*", "<em>hi</em>"},
+		{"link", "[go](https://go.dev)", `<a href="https://go.dev" rel="nofollow">go</a>`},
+		{"code language", "```go\nx := 1\n```", `<code class="language-go">`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, err := Render(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(html, tt.want) {
+				t.Errorf("got %q; want it to contain %q", html, tt.want)
+			}
+		})
+	}
+}
+
+// Classes other than the language of a code block are dropped
+func TestRenderDropsOtherClasses(t *testing.T) {
+	sanitized := policy.Sanitize(`<code class="evil">x</code><p class="language-go">y</p>`)
+	if strings.Contains(sanitized, "class") {
+		t.Errorf("got %q; want no classes", sanitized)
+	}
+}
@@ -0,0 +1,137 @@
+// Filename: internal/storage/local_test.go
+
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestLocal(t *testing.T) *Local {
+	t.Helper()
+	l, err := NewLocal(filepath.Join(t.TempDir(), "uploads"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func readAll(t *testing.T, l *Local, key string) string {
+	t.Helper()
+	r, err := l.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestLocalPutGet(t *testing.T) {
+	l := newTestLocal(t)
+	key := "abcdef0123"
+	if err := l.Put(key, strings.NewReader("first")); err != nil {
+		t.Fatal(err)
+	}
+	if got := readAll(t, l, key); got != "first" {
+		t.Errorf("got %q; want first", got)
+	}
+	// Writing the key again replaces the content
+	if err := l.Put(key, strings.NewReader("second")); err != nil {
+		t.Fatal(err)
+	}
+	if got := readAll(t, l, key); got != "second" {
+		t.Errorf("got %q; want second", got)
+	}
+	// Objects are spread over sub directories and no temporary file is left
+	entries, err := os.ReadDir(filepath.Join(l.root, "ab"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != key {
+		t.Errorf("got %v in the sub directory; want only %s", entries, key)
+	}
+}
+
+func TestLocalFailedPut(t *testing.T) {
+	l := newTestLocal(t)
+	key := "abcdef0123"
+	err := l.Put(key, io.MultiReader(strings.NewReader("partial"), errReader{}))
+	if err == nil {
+		t.Fatal("got no error for a failed read")
+	}
+	// Neither the object nor its temporary file is left behind
+	if exists, _ := l.Exists(key); exists {
+		t.Error("a failed Put stored the object")
+	}
+	entries, _ := os.ReadDir(filepath.Join(l.root, "ab"))
+	if len(entries) != 0 {
+		t.Errorf("got %v left in the sub directory; want nothing", entries)
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+func TestLocalMissing(t *testing.T) {
+	l := newTestLocal(t)
+	if _, err := l.Get("abcdef0123"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v; want ErrNotFound", err)
+	}
+	exists, err := l.Exists("abcdef0123")
+	if err != nil || exists {
+		t.Errorf("got %t, %v; want false", exists, err)
+	}
+	// Deleting a missing object is not an error
+	if err := l.Delete("abcdef0123"); err != nil {
+		t.Errorf("got %v deleting a missing object", err)
+	}
+}
+
+func TestLocalDelete(t *testing.T) {
+	l := newTestLocal(t)
+	key := "abcdef0123"
+	if err := l.Put(key, strings.NewReader("content")); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := l.Exists(key); err != nil || !exists {
+		t.Fatalf("got %t, %v; want true", exists, err)
+	}
+	if err := l.Delete(key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Get(key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v after Delete; want ErrNotFound", err)
+	}
+}
+
+// Keys cannot reach outside the root directory
+func TestLocalInvalidKeys(t *testing.T) {
+	l := newTestLocal(t)
+	for _, key := range []string{"", "ab", "../etc", "ab/cd", `ab\cd`, "abc.def", ".."} {
+		t.Run(key, func(t *testing.T) {
+			if err := l.Put(key, strings.NewReader("x")); err == nil {
+				t.Error("Put accepted the key")
+			}
+			if _, err := l.Get(key); err == nil || errors.Is(err, ErrNotFound) {
+				t.Errorf("Get returned %v; want an invalid key error", err)
+			}
+			if _, err := l.Exists(key); err == nil {
+				t.Error("Exists accepted the key")
+			}
+			if err := l.Delete(key); err == nil {
+				t.Error("Delete accepted the key")
+			}
+		})
+	}
+}
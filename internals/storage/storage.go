@@ -0,0 +1,27 @@
+// Filename: internal/storage/storage.go
+
+package storage
+
+import (
+	"errors"
+	"io"
+)
+
+var (
+	ErrNotFound = errors.New("object not found")
+)
+
+// Storage keeps the content of uploaded files. Objects are addressed by a key
+// chosen by the caller, and writing an existing key replaces its content.
+// Implementations must be safe for concurrent use
+type Storage interface {
+	// Put() stores the content read from r under key
+	Put(key string, r io.Reader) error
+	// Get() opens the object stored under key. It returns ErrNotFound if
+	// there is no such object
+	Get(key string) (io.ReadCloser, error)
+	// Exists() reports whether an object is stored under key
+	Exists(key string) (bool, error)
+	// Delete() removes the object stored under key, if any
+	Delete(key string) error
+}
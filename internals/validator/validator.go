@@ -32,7 +32,7 @@ func (v *Validator) Valid() bool {
 
 // In() checks if elements can be found in a provided list of elements
 func In(elements string, list ...string) bool {
-	for i := range elements {
+	for i := range list {
 
 		if elements == list[i] {
 			return true
@@ -0,0 +1,58 @@
+// Filename: internal/validator/validator_test.go
+
+package validator
+
+import "testing"
+
+// In() used to range over the bytes of the value instead of the list. It
+// then missed values further down the list than the value is long, and
+// panicked on a value longer than the list when nothing matched
+func TestIn(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		list  []string
+		want  bool
+	}{
+		{"first", "id", []string{"id", "title"}, true},
+		{"last", "-title", []string{"id", "title", "-id", "-title"}, true},
+		{"beyond the length of the value", "a", []string{"x", "y", "a"}, true},
+		{"missing", "likes", []string{"id", "title"}, false},
+		{"value longer than the list", "relevance", []string{"id"}, false},
+		{"empty value", "", []string{"id"}, false},
+		{"empty value listed", "", []string{"id", ""}, true},
+		{"empty list", "id", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := In(tt.value, tt.list...); got != tt.want {
+				t.Errorf("got %t; want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnique(t *testing.T) {
+	if !Unique([]string{"a", "b"}) {
+		t.Error("distinct values reported as repeated")
+	}
+	if Unique([]string{"a", "b", "a"}) {
+		t.Error("repeated values reported as unique")
+	}
+	if !Unique(nil) {
+		t.Error("no values reported as repeated")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	v := New()
+	v.Check(true, "name", "must be provided")
+	if !v.Valid() {
+		t.Fatal("a passing check added an error")
+	}
+	v.Check(false, "name", "must be provided")
+	v.Check(false, "name", "must be short")
+	if v.Valid() || v.Errors["name"] != "must be provided" {
+		t.Errorf("got %v; want the first error of name only", v.Errors)
+	}
+}
@@ -0,0 +1,6 @@
+-- Filename: migrations/000009_drop_replies_unique_constraint.down.sql
+
+DROP INDEX IF EXISTS replies_forums_id_idx;
+
+ALTER TABLE replies
+ADD CONSTRAINT replies_users_id_forums_id_key UNIQUE (users_id, forums_id);
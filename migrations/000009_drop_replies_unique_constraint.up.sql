@@ -0,0 +1,7 @@
+-- Filename: migrations/000009_drop_replies_unique_constraint.up.sql
+
+-- a user may reply to the same forum more than once
+ALTER TABLE replies
+DROP CONSTRAINT IF EXISTS replies_users_id_forums_id_key;
+
+CREATE INDEX IF NOT EXISTS replies_forums_id_idx ON replies (forums_id);
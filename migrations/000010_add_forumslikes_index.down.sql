@@ -0,0 +1,3 @@
+-- Filename: migrations/000010_add_forumslikes_index.down.sql
+
+DROP INDEX IF EXISTS forumslikes_forums_id_idx;
@@ -0,0 +1,4 @@
+-- Filename: migrations/000010_add_forumslikes_index.up.sql
+
+-- the unique (users_id, forums_id) index cannot be used to count the likes of a forum
+CREATE INDEX IF NOT EXISTS forumslikes_forums_id_idx ON forumslikes (forums_id);
@@ -0,0 +1,8 @@
+-- Filename: migrations/000011_add_author_to_forums.down.sql
+
+DELETE FROM permissions WHERE code = 'forums:moderate';
+
+DROP INDEX IF EXISTS forums_author_id_idx;
+
+ALTER TABLE forums
+DROP COLUMN IF EXISTS author_id;
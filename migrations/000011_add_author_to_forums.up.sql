@@ -0,0 +1,11 @@
+-- Filename: migrations/000011_add_author_to_forums.up.sql
+
+-- forums created before this migration have no author
+ALTER TABLE forums
+ADD COLUMN IF NOT EXISTS author_id bigint REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS forums_author_id_idx ON forums (author_id);
+
+-- moderators may edit and delete forums they did not create
+INSERT INTO permissions (code)
+VALUES ('forums:moderate');
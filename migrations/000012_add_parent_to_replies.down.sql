@@ -0,0 +1,6 @@
+-- Filename: migrations/000012_add_parent_to_replies.down.sql
+
+DROP INDEX IF EXISTS replies_parent_id_idx;
+
+ALTER TABLE replies
+DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,7 @@
+-- Filename: migrations/000012_add_parent_to_replies.up.sql
+
+-- a reply without a parent is a top level reply to the forum
+ALTER TABLE replies
+ADD COLUMN IF NOT EXISTS parent_id bigint REFERENCES replies (id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS replies_parent_id_idx ON replies (parent_id);
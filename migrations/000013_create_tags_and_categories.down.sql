@@ -0,0 +1,9 @@
+-- Filename: migrations/000013_create_tags_and_categories.down.sql
+
+DROP TABLE IF EXISTS forums_tags;
+DROP TABLE IF EXISTS tags;
+
+ALTER TABLE forums
+DROP COLUMN IF EXISTS category_id;
+
+DROP TABLE IF EXISTS categories;
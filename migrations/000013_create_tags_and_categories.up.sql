@@ -0,0 +1,33 @@
+-- Filename: migrations/000013_create_tags_and_categories.up.sql
+
+-- categories form a hierarchy, e.g. "Faculty of ICT > CMPS". The path of a
+-- category is stored so forums can be listed without walking the tree
+CREATE TABLE IF NOT EXISTS categories (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    name text NOT NULL,
+    parent_id bigint REFERENCES categories (id) ON DELETE CASCADE,
+    path text UNIQUE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS categories_parent_id_idx ON categories (parent_id);
+
+ALTER TABLE forums
+ADD COLUMN IF NOT EXISTS category_id bigint REFERENCES categories (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS forums_category_id_idx ON forums (category_id);
+
+-- tag names are stored in lowercase
+CREATE TABLE IF NOT EXISTS tags (
+    id bigserial PRIMARY KEY,
+    name text UNIQUE NOT NULL
+);
+
+-- a linking table between forums and tags (many to many)
+CREATE TABLE IF NOT EXISTS forums_tags (
+    forum_id bigint NOT NULL REFERENCES forums (id) ON DELETE CASCADE,
+    tag_id bigint NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (forum_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS forums_tags_tag_id_idx ON forums_tags (tag_id);
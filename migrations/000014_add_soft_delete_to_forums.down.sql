@@ -0,0 +1,15 @@
+-- Filename: migrations/000014_add_soft_delete_to_forums.down.sql
+
+ALTER TABLE forumslikes
+DROP CONSTRAINT IF EXISTS forumslikes_forums_id_fkey,
+ADD CONSTRAINT forumslikes_forums_id_fkey FOREIGN KEY (forums_id) REFERENCES forums (id);
+
+ALTER TABLE replies
+DROP CONSTRAINT IF EXISTS replies_forums_id_fkey,
+ADD CONSTRAINT replies_forums_id_fkey FOREIGN KEY (forums_id) REFERENCES forums (id);
+
+DROP INDEX IF EXISTS forums_deleted_at_idx;
+
+ALTER TABLE forums
+DROP COLUMN IF EXISTS deleted_by,
+DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,18 @@
+-- Filename: migrations/000014_add_soft_delete_to_forums.up.sql
+
+-- a forum with a deleted_at time is in the trash
+ALTER TABLE forums
+ADD COLUMN IF NOT EXISTS deleted_at timestamp(0) with time zone,
+ADD COLUMN IF NOT EXISTS deleted_by bigint REFERENCES users (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS forums_deleted_at_idx ON forums (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- forums are only removed for good when the trash is purged, and then their
+-- replies and likes go with them
+ALTER TABLE replies
+DROP CONSTRAINT IF EXISTS replies_forums_id_fkey,
+ADD CONSTRAINT replies_forums_id_fkey FOREIGN KEY (forums_id) REFERENCES forums (id) ON DELETE CASCADE;
+
+ALTER TABLE forumslikes
+DROP CONSTRAINT IF EXISTS forumslikes_forums_id_fkey,
+ADD CONSTRAINT forumslikes_forums_id_fkey FOREIGN KEY (forums_id) REFERENCES forums (id) ON DELETE CASCADE;
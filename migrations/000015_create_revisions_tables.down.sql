@@ -0,0 +1,4 @@
+-- Filename: migrations/000015_create_revisions_tables.down.sql
+
+DROP TABLE IF EXISTS reply_revisions;
+DROP TABLE IF EXISTS forum_revisions;
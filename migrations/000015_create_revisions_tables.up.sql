@@ -0,0 +1,24 @@
+-- Filename: migrations/000015_create_revisions_tables.up.sql
+
+-- each row holds the content of a forum at a version that has since been
+-- replaced, along with who replaced it and when
+CREATE TABLE IF NOT EXISTS forum_revisions (
+    id bigserial PRIMARY KEY,
+    forum_id bigint NOT NULL REFERENCES forums (id) ON DELETE CASCADE,
+    version integer NOT NULL,
+    title text NOT NULL,
+    description text NOT NULL,
+    edited_by bigint REFERENCES users (id) ON DELETE SET NULL,
+    edited_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    UNIQUE (forum_id, version)
+);
+
+CREATE TABLE IF NOT EXISTS reply_revisions (
+    id bigserial PRIMARY KEY,
+    reply_id bigint NOT NULL REFERENCES replies (id) ON DELETE CASCADE,
+    version integer NOT NULL,
+    message text NOT NULL,
+    edited_by bigint REFERENCES users (id) ON DELETE SET NULL,
+    edited_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    UNIQUE (reply_id, version)
+);
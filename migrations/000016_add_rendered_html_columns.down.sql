@@ -0,0 +1,7 @@
+-- Filename: migrations/000016_add_rendered_html_columns.down.sql
+
+ALTER TABLE replies
+DROP COLUMN IF EXISTS message_html;
+
+ALTER TABLE forums
+DROP COLUMN IF EXISTS description_html;
@@ -0,0 +1,15 @@
+-- Filename: migrations/000016_add_rendered_html_columns.up.sql
+
+-- the sanitized HTML rendered from the markdown source is cached alongside it
+ALTER TABLE forums
+ADD COLUMN IF NOT EXISTS description_html text NOT NULL DEFAULT '';
+
+ALTER TABLE replies
+ADD COLUMN IF NOT EXISTS message_html text NOT NULL DEFAULT '';
+
+-- existing posts are plain text, so they render as a single escaped paragraph
+UPDATE forums
+SET description_html = '<p>' || replace(replace(replace(description, '&', '&amp;'), '<', '&lt;'), '>', '&gt;') || '</p>';
+
+UPDATE replies
+SET message_html = '<p>' || replace(replace(replace(message, '&', '&amp;'), '<', '&lt;'), '>', '&gt;') || '</p>';
@@ -0,0 +1,3 @@
+-- Filename: migrations/000017_create_attachments_table.down.sql
+
+DROP TABLE IF EXISTS attachments;
@@ -0,0 +1,21 @@
+-- Filename: migrations/000017_create_attachments_table.up.sql
+
+-- an attachment belongs to either a forum or a reply. The file content is
+-- kept in the storage under the hex encoded sha256, so identical uploads are
+-- stored once
+CREATE TABLE IF NOT EXISTS attachments (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    forum_id bigint REFERENCES forums (id) ON DELETE CASCADE,
+    reply_id bigint REFERENCES replies (id) ON DELETE CASCADE,
+    uploader_id bigint REFERENCES users (id) ON DELETE SET NULL,
+    filename text NOT NULL,
+    content_type text NOT NULL,
+    size bigint NOT NULL,
+    sha256 bytea NOT NULL,
+    CHECK ((forum_id IS NULL) <> (reply_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS attachments_forum_id_idx ON attachments (forum_id);
+CREATE INDEX IF NOT EXISTS attachments_reply_id_idx ON attachments (reply_id);
+CREATE INDEX IF NOT EXISTS attachments_sha256_idx ON attachments (sha256);
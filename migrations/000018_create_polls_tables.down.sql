@@ -0,0 +1,6 @@
+-- Filename: migrations/000018_create_polls_tables.down.sql
+
+DROP TABLE IF EXISTS poll_votes;
+DROP TABLE IF EXISTS poll_ballots;
+DROP TABLE IF EXISTS poll_options;
+DROP TABLE IF EXISTS polls;
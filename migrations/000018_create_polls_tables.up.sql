@@ -0,0 +1,43 @@
+-- Filename: migrations/000018_create_polls_tables.up.sql
+
+-- a forum carries at most one poll
+CREATE TABLE IF NOT EXISTS polls (
+    forum_id bigint PRIMARY KEY REFERENCES forums (id) ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    question text NOT NULL,
+    multiple_choice boolean NOT NULL DEFAULT false,
+    anonymous boolean NOT NULL DEFAULT false,
+    hide_results boolean NOT NULL DEFAULT false,
+    closes_at timestamp(0) with time zone
+);
+
+CREATE TABLE IF NOT EXISTS poll_options (
+    id bigserial PRIMARY KEY,
+    forum_id bigint NOT NULL REFERENCES polls (forum_id) ON DELETE CASCADE,
+    position integer NOT NULL,
+    text text NOT NULL,
+    UNIQUE (forum_id, position),
+    -- lets votes check that the option belongs to their poll
+    UNIQUE (id, forum_id)
+);
+
+-- a ballot is cast once per user and poll, the primary key is what enforces
+-- one vote per user
+CREATE TABLE IF NOT EXISTS poll_ballots (
+    forum_id bigint NOT NULL REFERENCES polls (forum_id) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (forum_id, user_id)
+);
+
+-- the options chosen on a ballot, more than one on multiple choice polls
+CREATE TABLE IF NOT EXISTS poll_votes (
+    forum_id bigint NOT NULL,
+    user_id bigint NOT NULL,
+    option_id bigint NOT NULL,
+    PRIMARY KEY (forum_id, user_id, option_id),
+    FOREIGN KEY (forum_id, user_id) REFERENCES poll_ballots (forum_id, user_id) ON DELETE CASCADE,
+    FOREIGN KEY (option_id, forum_id) REFERENCES poll_options (id, forum_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS poll_votes_option_id_idx ON poll_votes (option_id);
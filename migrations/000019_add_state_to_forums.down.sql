@@ -0,0 +1,9 @@
+-- Filename: migrations/000019_add_state_to_forums.down.sql
+
+DROP INDEX IF EXISTS forums_pinned_idx;
+
+ALTER TABLE forums
+DROP CONSTRAINT IF EXISTS forums_kind_check,
+DROP COLUMN IF EXISTS kind,
+DROP COLUMN IF EXISTS locked,
+DROP COLUMN IF EXISTS pinned;
@@ -0,0 +1,12 @@
+-- Filename: migrations/000019_add_state_to_forums.up.sql
+
+-- pinned forums are listed first, locked forums take no new replies
+ALTER TABLE forums
+ADD COLUMN IF NOT EXISTS pinned boolean NOT NULL DEFAULT false,
+ADD COLUMN IF NOT EXISTS locked boolean NOT NULL DEFAULT false,
+ADD COLUMN IF NOT EXISTS kind text NOT NULL DEFAULT 'discussion';
+
+ALTER TABLE forums
+ADD CONSTRAINT forums_kind_check CHECK (kind IN ('discussion', 'question', 'announcement'));
+
+CREATE INDEX IF NOT EXISTS forums_pinned_idx ON forums (pinned) WHERE pinned;
@@ -0,0 +1,4 @@
+-- Filename: migrations/000020_add_accepted_reply_to_forums.down.sql
+
+ALTER TABLE forums
+DROP COLUMN IF EXISTS accepted_reply_id;
@@ -0,0 +1,5 @@
+-- Filename: migrations/000020_add_accepted_reply_to_forums.up.sql
+
+-- the reply accepted as the answer to a question forum
+ALTER TABLE forums
+ADD COLUMN IF NOT EXISTS accepted_reply_id bigint REFERENCES replies (id) ON DELETE SET NULL;
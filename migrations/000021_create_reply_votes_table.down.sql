@@ -0,0 +1,11 @@
+-- Filename: migrations/000021_create_reply_votes_table.down.sql
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS reputation;
+
+DROP INDEX IF EXISTS replies_forums_id_score_idx;
+
+ALTER TABLE replies
+DROP COLUMN IF EXISTS score;
+
+DROP TABLE IF EXISTS reply_votes;
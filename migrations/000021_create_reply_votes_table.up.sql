@@ -0,0 +1,22 @@
+-- Filename: migrations/000021_create_reply_votes_table.up.sql
+
+-- a user votes a reply up (1) or down (-1) once
+CREATE TABLE IF NOT EXISTS reply_votes (
+    reply_id bigint NOT NULL REFERENCES replies (id) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    value smallint NOT NULL CHECK (value IN (-1, 1)),
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (reply_id, user_id)
+);
+
+-- the score is the sum of the votes, kept on the reply so listings can be
+-- sorted by it
+ALTER TABLE replies
+ADD COLUMN IF NOT EXISTS score integer NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS replies_forums_id_score_idx ON replies (forums_id, score);
+
+-- the reputation is derived from the votes and accepted answers a user
+-- received and can be recomputed at any time
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS reputation integer NOT NULL DEFAULT 0;
@@ -0,0 +1,9 @@
+-- Filename: migrations/000022_create_mentions_table.down.sql
+
+ALTER TABLE replies
+DROP COLUMN IF EXISTS mentions;
+
+ALTER TABLE forums
+DROP COLUMN IF EXISTS mentions;
+
+DROP TABLE IF EXISTS mentions;
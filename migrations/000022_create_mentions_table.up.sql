@@ -0,0 +1,24 @@
+-- Filename: migrations/000022_create_mentions_table.up.sql
+
+-- a user mentioned in a forum description or a reply. The row is kept when
+-- the mention is edited out so that adding it back does not notify again
+CREATE TABLE IF NOT EXISTS mentions (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    forum_id bigint REFERENCES forums (id) ON DELETE CASCADE,
+    reply_id bigint REFERENCES replies (id) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    CHECK ((forum_id IS NULL) <> (reply_id IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS mentions_forum_id_user_id_idx ON mentions (forum_id, user_id) WHERE forum_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS mentions_reply_id_user_id_idx ON mentions (reply_id, user_id) WHERE reply_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS mentions_user_id_idx ON mentions (user_id);
+
+-- the resolved mentions of the current text, stored alongside the rendered
+-- HTML so reads do not have to resolve them
+ALTER TABLE forums
+ADD COLUMN IF NOT EXISTS mentions jsonb NOT NULL DEFAULT '[]';
+
+ALTER TABLE replies
+ADD COLUMN IF NOT EXISTS mentions jsonb NOT NULL DEFAULT '[]';
@@ -0,0 +1,3 @@
+-- Filename: migrations/000023_create_forum_subscriptions_table.down.sql
+
+DROP TABLE IF EXISTS forum_subscriptions;
@@ -0,0 +1,14 @@
+-- Filename: migrations/000023_create_forum_subscriptions_table.up.sql
+
+-- the users following a forum. Authors and repliers are subscribed
+-- automatically; a row with subscribed = false records that the user opted
+-- out, so posting again does not subscribe them back
+CREATE TABLE IF NOT EXISTS forum_subscriptions (
+    forum_id bigint NOT NULL REFERENCES forums (id) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    subscribed boolean NOT NULL DEFAULT true,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (forum_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS forum_subscriptions_user_id_idx ON forum_subscriptions (user_id);
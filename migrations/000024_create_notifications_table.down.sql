@@ -0,0 +1,3 @@
+-- Filename: migrations/000024_create_notifications_table.down.sql
+
+DROP TABLE IF EXISTS notifications;
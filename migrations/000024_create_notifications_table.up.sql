@@ -0,0 +1,15 @@
+-- Filename: migrations/000024_create_notifications_table.up.sql
+
+-- the in-app inbox of a user. The payload carries whatever the type needs,
+-- such as the forum, the reply and who acted
+CREATE TABLE IF NOT EXISTS notifications (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    type text NOT NULL CHECK (type IN ('reply', 'mention', 'like', 'moderation')),
+    payload jsonb NOT NULL DEFAULT '{}',
+    read_at timestamp(0) with time zone
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_id_idx ON notifications (user_id, id);
+CREATE INDEX IF NOT EXISTS notifications_unread_idx ON notifications (user_id) WHERE read_at IS NULL;
@@ -0,0 +1,3 @@
+-- Filename: migrations/000025_create_bookmarks_table.down.sql
+
+DROP TABLE IF EXISTS bookmarks;
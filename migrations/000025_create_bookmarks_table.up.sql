@@ -0,0 +1,15 @@
+-- Filename: migrations/000025_create_bookmarks_table.up.sql
+
+-- the forums saved by a user, with an optional folder and personal note.
+-- An empty folder means the bookmark is not filed anywhere
+CREATE TABLE IF NOT EXISTS bookmarks (
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    forum_id bigint NOT NULL REFERENCES forums (id) ON DELETE CASCADE,
+    folder text NOT NULL DEFAULT '',
+    note text NOT NULL DEFAULT '',
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, forum_id)
+);
+
+CREATE INDEX IF NOT EXISTS bookmarks_user_id_folder_idx ON bookmarks (user_id, folder);
+CREATE INDEX IF NOT EXISTS bookmarks_forum_id_idx ON bookmarks (forum_id);
@@ -0,0 +1,16 @@
+-- Filename: migrations/000026_add_status_to_forums.down.sql
+
+DELETE FROM notifications WHERE type = 'published';
+
+ALTER TABLE notifications
+DROP CONSTRAINT IF EXISTS notifications_type_check,
+ADD CONSTRAINT notifications_type_check CHECK (type IN ('reply', 'mention', 'like', 'moderation'));
+
+DROP INDEX IF EXISTS forums_publish_at_idx;
+
+ALTER TABLE forums
+DROP CONSTRAINT IF EXISTS forums_publish_at_check,
+DROP CONSTRAINT IF EXISTS forums_status_check,
+DROP COLUMN IF EXISTS published_at,
+DROP COLUMN IF EXISTS publish_at,
+DROP COLUMN IF EXISTS status;
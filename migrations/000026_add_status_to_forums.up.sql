@@ -0,0 +1,21 @@
+-- Filename: migrations/000026_add_status_to_forums.up.sql
+
+-- drafts and scheduled forums are only visible to their author until they
+-- are published. Scheduled forums are published once publish_at has passed
+ALTER TABLE forums
+ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'published',
+ADD COLUMN IF NOT EXISTS publish_at timestamp(0) with time zone,
+ADD COLUMN IF NOT EXISTS published_at timestamp(0) with time zone;
+
+UPDATE forums SET published_at = created_at;
+
+ALTER TABLE forums
+ADD CONSTRAINT forums_status_check CHECK (status IN ('draft', 'scheduled', 'published')),
+ADD CONSTRAINT forums_publish_at_check CHECK (status <> 'scheduled' OR publish_at IS NOT NULL);
+
+CREATE INDEX IF NOT EXISTS forums_publish_at_idx ON forums (publish_at) WHERE status = 'scheduled';
+
+-- subscribers are told when a scheduled forum goes out
+ALTER TABLE notifications
+DROP CONSTRAINT IF EXISTS notifications_type_check,
+ADD CONSTRAINT notifications_type_check CHECK (type IN ('reply', 'mention', 'like', 'moderation', 'published'));
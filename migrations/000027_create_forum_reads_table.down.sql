@@ -0,0 +1,5 @@
+-- Filename: migrations/000027_create_forum_reads_table.down.sql
+
+DROP INDEX IF EXISTS replies_forums_id_id_idx;
+
+DROP TABLE IF EXISTS forum_reads;
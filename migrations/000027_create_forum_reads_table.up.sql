@@ -0,0 +1,14 @@
+-- Filename: migrations/000027_create_forum_reads_table.up.sql
+
+-- how far a user has read a forum. The replies after last_read_reply_id are
+-- unread; 0 means none of the replies were read
+CREATE TABLE IF NOT EXISTS forum_reads (
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    forum_id bigint NOT NULL REFERENCES forums (id) ON DELETE CASCADE,
+    last_read_reply_id bigint NOT NULL DEFAULT 0,
+    read_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, forum_id)
+);
+
+-- counting the replies after a position
+CREATE INDEX IF NOT EXISTS replies_forums_id_id_idx ON replies (forums_id, id);
@@ -0,0 +1,3 @@
+-- Filename: migrations/000028_create_reactions_table.down.sql
+
+DROP TABLE IF EXISTS reactions;
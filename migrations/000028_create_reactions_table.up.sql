@@ -0,0 +1,17 @@
+-- Filename: migrations/000028_create_reactions_table.up.sql
+
+-- an emoji reaction of a user to a forum or a reply. Which emojis can be
+-- used is configured on the server; each one counts once per user
+CREATE TABLE IF NOT EXISTS reactions (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    forum_id bigint REFERENCES forums (id) ON DELETE CASCADE,
+    reply_id bigint REFERENCES replies (id) ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    emoji text NOT NULL,
+    CHECK ((forum_id IS NULL) <> (reply_id IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS reactions_forum_id_user_id_emoji_idx ON reactions (forum_id, user_id, emoji) WHERE forum_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS reactions_reply_id_user_id_emoji_idx ON reactions (reply_id, user_id, emoji) WHERE reply_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS reactions_user_id_idx ON reactions (user_id);
@@ -0,0 +1,16 @@
+-- Filename: migrations/000029_add_search_vectors.down.sql
+
+DROP INDEX IF EXISTS replies_search_vector_idx;
+DROP INDEX IF EXISTS forums_search_vector_idx;
+
+DROP TRIGGER IF EXISTS replies_forum_search_vector_update ON replies;
+DROP TRIGGER IF EXISTS replies_search_vector_update ON replies;
+DROP TRIGGER IF EXISTS forums_search_vector_update ON forums;
+
+DROP FUNCTION IF EXISTS replies_forum_search_vector_trigger();
+DROP FUNCTION IF EXISTS replies_search_vector_trigger();
+DROP FUNCTION IF EXISTS forums_search_vector_trigger();
+DROP FUNCTION IF EXISTS forum_search_vector(bigint, text, text);
+
+ALTER TABLE replies DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE forums DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,69 @@
+-- Filename: migrations/000029_add_search_vectors.up.sql
+
+-- the weighted search documents. A forum is found by its title (A), its
+-- description (B) and the text of its replies (C); a reply by its message
+ALTER TABLE forums
+ADD COLUMN IF NOT EXISTS search_vector tsvector NOT NULL DEFAULT '';
+
+ALTER TABLE replies
+ADD COLUMN IF NOT EXISTS search_vector tsvector NOT NULL DEFAULT '';
+
+-- the search document of a forum. The replies of very long threads are cut
+-- short so the document stays under the size limit of a tsvector
+CREATE OR REPLACE FUNCTION forum_search_vector(forum bigint, title text, description text) RETURNS tsvector AS $$
+    SELECT setweight(to_tsvector('simple', COALESCE(title, '')), 'A') ||
+           setweight(to_tsvector('simple', COALESCE(description, '')), 'B') ||
+           setweight(to_tsvector('simple', left(COALESCE(string_agg(replies.message, ' ' ORDER BY replies.id), ''), 200000)), 'C')
+    FROM replies
+    WHERE replies.forums_id = forum
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION forums_search_vector_trigger() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector := forum_search_vector(NEW.id, NEW.title, NEW.description);
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER forums_search_vector_update
+BEFORE INSERT OR UPDATE OF title, description ON forums
+FOR EACH ROW EXECUTE PROCEDURE forums_search_vector_trigger();
+
+CREATE OR REPLACE FUNCTION replies_search_vector_trigger() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector := setweight(to_tsvector('simple', NEW.message), 'C');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER replies_search_vector_update
+BEFORE INSERT OR UPDATE OF message ON replies
+FOR EACH ROW EXECUTE PROCEDURE replies_search_vector_trigger();
+
+-- the replies are part of the document of their forum
+CREATE OR REPLACE FUNCTION replies_forum_search_vector_trigger() RETURNS trigger AS $$
+DECLARE
+    forum bigint;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        forum := OLD.forums_id;
+    ELSE
+        forum := NEW.forums_id;
+    END IF;
+    UPDATE forums
+    SET search_vector = forum_search_vector(forums.id, forums.title, forums.description)
+    WHERE forums.id = forum;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER replies_forum_search_vector_update
+AFTER INSERT OR DELETE OR UPDATE OF message ON replies
+FOR EACH ROW EXECUTE PROCEDURE replies_forum_search_vector_trigger();
+
+-- build the documents of the existing forums and replies
+UPDATE replies SET search_vector = setweight(to_tsvector('simple', message), 'C');
+UPDATE forums SET search_vector = forum_search_vector(id, title, description);
+
+CREATE INDEX IF NOT EXISTS forums_search_vector_idx ON forums USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS replies_search_vector_idx ON replies USING GIN (search_vector);
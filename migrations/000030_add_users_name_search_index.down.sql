@@ -0,0 +1,3 @@
+-- Filename: migrations/000030_add_users_name_search_index.down.sql
+
+DROP INDEX IF EXISTS users_name_search_idx;
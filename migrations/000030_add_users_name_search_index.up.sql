@@ -0,0 +1,4 @@
+-- Filename: migrations/000030_add_users_name_search_index.up.sql
+
+-- users are found by their name in the unified search
+CREATE INDEX IF NOT EXISTS users_name_search_idx ON users USING GIN (to_tsvector('simple', name));
@@ -0,0 +1,4 @@
+-- Filename: migrations/000031_add_forums_title_trigram_index.down.sql
+
+-- pg_trgm may have been there before, or be used by others, so it stays
+DROP INDEX IF EXISTS forums_title_trgm_idx;
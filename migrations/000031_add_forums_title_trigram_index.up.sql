@@ -0,0 +1,20 @@
+-- Filename: migrations/000031_add_forums_title_trigram_index.up.sql
+
+-- pg_trgm backs the typo tolerant title suggestions. It is part of contrib
+-- and may be missing, or need privileges we do not have; the suggestions then
+-- fall back to substring matching, so its absence is not an error here
+DO $$
+BEGIN
+    CREATE EXTENSION IF NOT EXISTS pg_trgm;
+EXCEPTION WHEN OTHERS THEN
+    RAISE NOTICE 'pg_trgm is not available: %', SQLERRM;
+END
+$$;
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm') THEN
+        CREATE INDEX IF NOT EXISTS forums_title_trgm_idx ON forums USING GIN (title gin_trgm_ops);
+    END IF;
+END
+$$;
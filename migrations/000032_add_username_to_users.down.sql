@@ -0,0 +1,9 @@
+-- Filename: migrations/000032_add_username_to_users.down.sql
+
+DROP TRIGGER IF EXISTS users_username ON users;
+DROP FUNCTION IF EXISTS users_username_trigger();
+
+DROP INDEX IF EXISTS users_username_key;
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS username;
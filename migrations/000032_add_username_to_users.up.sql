@@ -0,0 +1,29 @@
+-- Filename: migrations/000032_add_username_to_users.up.sql
+
+-- users are mentioned by a public username rather than by anything taken
+-- from their email address. Users who do not choose one, including the
+-- existing users, are named after their id
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS username citext;
+
+UPDATE users
+SET username = 'user' || id
+WHERE username IS NULL;
+
+ALTER TABLE users
+ALTER COLUMN username SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_key ON users (username);
+
+CREATE OR REPLACE FUNCTION users_username_trigger() RETURNS trigger AS $$
+BEGIN
+    IF NEW.username IS NULL THEN
+        NEW.username := 'user' || NEW.id;
+    END IF;
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER users_username
+BEFORE INSERT ON users
+FOR EACH ROW EXECUTE PROCEDURE users_username_trigger();
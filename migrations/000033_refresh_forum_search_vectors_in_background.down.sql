@@ -0,0 +1,31 @@
+-- Filename: migrations/000033_refresh_forum_search_vectors_in_background.down.sql
+
+CREATE OR REPLACE FUNCTION forums_search_vector_trigger() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector := forum_search_vector(NEW.id, NEW.title, NEW.description);
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION replies_forum_search_vector_trigger() RETURNS trigger AS $$
+DECLARE
+    forum bigint;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        forum := OLD.forums_id;
+    ELSE
+        forum := NEW.forums_id;
+    END IF;
+    UPDATE forums
+    SET search_vector = forum_search_vector(forums.id, forums.title, forums.description)
+    WHERE forums.id = forum;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+-- bring the documents still queued up to date
+UPDATE forums
+SET search_vector = forum_search_vector(id, title, description)
+WHERE id IN (SELECT forum_id FROM forum_search_refreshes);
+
+DROP TABLE IF EXISTS forum_search_refreshes;
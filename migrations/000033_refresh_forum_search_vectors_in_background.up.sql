@@ -0,0 +1,33 @@
+-- Filename: migrations/000033_refresh_forum_search_vectors_in_background.up.sql
+
+-- rebuilding the document of a forum on every reply went over the whole
+-- thread and locked the forum row. The replies now only queue their forum,
+-- without a unique key so that writers never wait on each other, and the
+-- server rebuilds the queued documents in the background
+CREATE TABLE IF NOT EXISTS forum_search_refreshes (
+    id bigserial PRIMARY KEY,
+    forum_id bigint NOT NULL
+);
+
+CREATE OR REPLACE FUNCTION replies_forum_search_vector_trigger() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO forum_search_refreshes (forum_id) VALUES (OLD.forums_id);
+    ELSE
+        INSERT INTO forum_search_refreshes (forum_id) VALUES (NEW.forums_id);
+    END IF;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+-- editing a forum keeps the replies part (C) of its document as it is
+CREATE OR REPLACE FUNCTION forums_search_vector_trigger() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector := setweight(to_tsvector('simple', COALESCE(NEW.title, '')), 'A') ||
+                         setweight(to_tsvector('simple', COALESCE(NEW.description, '')), 'B');
+    IF TG_OP = 'UPDATE' THEN
+        NEW.search_vector := NEW.search_vector || ts_filter(OLD.search_vector, '{c}');
+    END IF;
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
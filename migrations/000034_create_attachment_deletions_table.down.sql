@@ -0,0 +1,5 @@
+-- Filename: migrations/000034_create_attachment_deletions_table.down.sql
+
+DROP TRIGGER IF EXISTS attachments_deletion ON attachments;
+DROP FUNCTION IF EXISTS attachments_deletion_trigger();
+DROP TABLE IF EXISTS attachment_deletions;
@@ -0,0 +1,20 @@
+-- Filename: migrations/000034_create_attachment_deletions_table.up.sql
+
+-- attachments go away with their forum or reply, but their content is in the
+-- storage. The content of every deleted attachment is queued here so the
+-- server can remove it once no other attachment shares it
+CREATE TABLE IF NOT EXISTS attachment_deletions (
+    id bigserial PRIMARY KEY,
+    sha256 bytea NOT NULL
+);
+
+CREATE OR REPLACE FUNCTION attachments_deletion_trigger() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO attachment_deletions (sha256) VALUES (OLD.sha256);
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER attachments_deletion
+AFTER DELETE ON attachments
+FOR EACH ROW EXECUTE PROCEDURE attachments_deletion_trigger();
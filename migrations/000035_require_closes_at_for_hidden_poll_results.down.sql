@@ -0,0 +1,4 @@
+-- Filename: migrations/000035_require_closes_at_for_hidden_poll_results.down.sql
+
+ALTER TABLE polls
+DROP CONSTRAINT IF EXISTS polls_hide_results_check;
@@ -0,0 +1,8 @@
+-- Filename: migrations/000035_require_closes_at_for_hidden_poll_results.up.sql
+
+-- hidden results are revealed when the poll closes, so a poll that never
+-- closes cannot hide them. Existing ones show their results from now on
+UPDATE polls SET hide_results = false WHERE hide_results AND closes_at IS NULL;
+
+ALTER TABLE polls
+ADD CONSTRAINT polls_hide_results_check CHECK (NOT hide_results OR closes_at IS NOT NULL);